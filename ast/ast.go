@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/pandeykartikey/goto/token"
@@ -9,6 +10,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -33,6 +35,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out strings.Builder
 
@@ -54,10 +64,33 @@ func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+func (il *IntegerLiteral) Pos() token.Position {
+	return il.Token.Pos
+}
+
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FloatLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -69,6 +102,10 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
 func (b *Boolean) String() string {
 	return b.Token.Literal
 }
@@ -84,10 +121,47 @@ func (s *String) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (s *String) Pos() token.Position {
+	return s.Token.Pos
+}
+
 func (s *String) String() string {
 	return s.Token.Literal
 }
 
+// InterpolatedString is a string such as "a ${b} c". Parts alternates between the literal text,
+// stored as *String, and the interpolated expressions, so it always has an odd length.
+type InterpolatedString struct {
+	Token token.Token // the TEMPLATE_HEAD token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode() {}
+
+func (is *InterpolatedString) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *InterpolatedString) Pos() token.Position {
+	return is.Token.Pos
+}
+
+func (is *InterpolatedString) String() string {
+	var out strings.Builder
+
+	for idx, part := range is.Parts {
+		if idx%2 == 0 {
+			out.WriteString(part.String())
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -99,13 +173,17 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i *Identifier) String() string {
 	return i.Value
 }
 
 type Assignment struct {
 	Token        token.Token
-	NameList     *IdentifierList
+	TargetList   *ExpressionList // identifiers, or unless declared with var or const also index and field expressions
 	ValueList    *ExpressionList
 	IsExpression bool // to check whether it acting as expression or statement
 }
@@ -118,16 +196,27 @@ func (as *Assignment) TokenLiteral() string {
 	return as.Token.Literal
 }
 
+func (as *Assignment) Pos() token.Position {
+	return as.Token.Pos
+}
+
 func (as *Assignment) String() string {
 	var out strings.Builder
-	if as.Token.Literal == "var" {
-		out.WriteString("var ")
+	if as.Token.Type == token.VAR || as.Token.Type == token.CONST {
+		out.WriteString(as.Token.Literal + " ")
 	}
 
-	out.WriteString(as.NameList.String())
+	out.WriteString(as.TargetList.String())
 
-	if as.ValueList != nil {
-		out.WriteString(" = ")
+	switch {
+	case as.Token.Type == token.INC || as.Token.Type == token.DEC:
+		out.WriteString(as.Token.Literal)
+	case as.ValueList != nil:
+		if as.Token.Type == token.VAR || as.Token.Type == token.CONST {
+			out.WriteString(" = ")
+		} else {
+			out.WriteString(" " + as.Token.Literal + " ")
+		}
 		out.WriteString(as.ValueList.String())
 	}
 	if !as.IsExpression {
@@ -148,6 +237,10 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
+
 func (rs *ReturnStatement) String() string {
 	var out strings.Builder
 
@@ -174,6 +267,10 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos
+}
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -194,6 +291,10 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
 func (pe *PrefixExpression) String() string {
 	var out strings.Builder
 
@@ -218,6 +319,10 @@ func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *InfixExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 func (ie *InfixExpression) String() string {
 	var out strings.Builder
 
@@ -232,6 +337,37 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+type ConditionalExpression struct {
+	Token       token.Token // the '?'
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode() {}
+
+func (ce *ConditionalExpression) TokenLiteral() string {
+	return ce.Token.Literal
+}
+
+func (ce *ConditionalExpression) Pos() token.Position {
+	return ce.Condition.Pos()
+}
+
+func (ce *ConditionalExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
@@ -243,6 +379,10 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
 func (bs *BlockStatement) String() string {
 	var out strings.Builder
 	out.WriteString("{ ")
@@ -270,6 +410,10 @@ func (is *IfStatement) TokenLiteral() string {
 	return is.Token.Literal
 }
 
+func (is *IfStatement) Pos() token.Position {
+	return is.Token.Pos
+}
+
 func (is *IfStatement) String() string {
 	var out strings.Builder
 	out.WriteString(is.TokenLiteral())
@@ -291,6 +435,88 @@ func (is *IfStatement) String() string {
 	return out.String()
 }
 
+// TryStatement is `try { } catch e { } finally { }`. At least one of Catch and Finally is set.
+type TryStatement struct {
+	Token     token.Token
+	Body      *BlockStatement
+	CatchName *Identifier // nil if the caught error is not bound to a name
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (ts *TryStatement) statementNode() {}
+
+func (ts *TryStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *TryStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+func (ts *TryStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString(ts.TokenLiteral())
+	out.WriteString(" ")
+	out.WriteString(ts.Body.String())
+
+	if ts.Catch != nil {
+		out.WriteString(" catch ")
+		if ts.CatchName != nil {
+			out.WriteString(ts.CatchName.String())
+			out.WriteString(" ")
+		}
+		out.WriteString(ts.Catch.String())
+	}
+
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+
+	return out.String()
+}
+
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *ThrowStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+// DeferStatement is `defer call(args);`. The call is made when the enclosing function returns.
+type DeferStatement struct {
+	Token token.Token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode() {}
+
+func (ds *DeferStatement) TokenLiteral() string {
+	return ds.Token.Literal
+}
+
+func (ds *DeferStatement) Pos() token.Position {
+	return ds.Token.Pos
+}
+
+func (ds *DeferStatement) String() string {
+	return ds.TokenLiteral() + " " + ds.Call.String() + ";"
+}
+
 type IdentifierList struct {
 	Token       token.Token
 	Identifiers []*Identifier
@@ -302,6 +528,10 @@ func (il *IdentifierList) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+func (il *IdentifierList) Pos() token.Position {
+	return il.Token.Pos
+}
+
 func (il *IdentifierList) String() string {
 	var out strings.Builder
 
@@ -318,10 +548,77 @@ func (il *IdentifierList) String() string {
 	return out.String()
 }
 
+// ParameterList holds the parameters of a function. Parameters with a default value may be left out
+// by the caller, and Rest collects any remaining positional arguments into a list.
+type ParameterList struct {
+	Token       token.Token
+	Identifiers []*Identifier
+	Defaults    []Expression // Defaults[i] belongs to Identifiers[i], nil for required parameters
+	Rest        *Identifier  // the ...rest parameter, nil if there is none
+}
+
+func (pl *ParameterList) expressionNode() {}
+
+func (pl *ParameterList) TokenLiteral() string {
+	return pl.Token.Literal
+}
+
+func (pl *ParameterList) Pos() token.Position {
+	return pl.Token.Pos
+}
+
+func (pl *ParameterList) String() string {
+	var out strings.Builder
+
+	if pl != nil {
+		for idx, param := range pl.Identifiers {
+			if idx > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString(param.String())
+			if pl.Defaults[idx] != nil {
+				out.WriteString(" = ")
+				out.WriteString(pl.Defaults[idx].String())
+			}
+		}
+		if pl.Rest != nil {
+			if len(pl.Identifiers) > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString("...")
+			out.WriteString(pl.Rest.String())
+		}
+	}
+
+	return out.String()
+}
+
+// KeywordArgument is an argument passed by name, `name: value`
+type KeywordArgument struct {
+	Token token.Token // the name
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode() {}
+
+func (ka *KeywordArgument) TokenLiteral() string {
+	return ka.Token.Literal
+}
+
+func (ka *KeywordArgument) Pos() token.Position {
+	return ka.Token.Pos
+}
+
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
+}
+
 type FuncStatement struct {
 	Token         token.Token
+	Doc           string // text of the ## comments directly above the function
 	Name          *Identifier
-	ParameterList *IdentifierList
+	ParameterList *ParameterList
 	FuncBody      *BlockStatement
 }
 
@@ -331,6 +628,10 @@ func (fs *FuncStatement) TokenLiteral() string {
 	return fs.Token.Literal
 }
 
+func (fs *FuncStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
 func (fs *FuncStatement) String() string {
 	var out strings.Builder
 
@@ -345,6 +646,99 @@ func (fs *FuncStatement) String() string {
 	return out.String()
 }
 
+// StructStatement declares a struct type, `struct Point { x, y = 0; func norm() { ... } }`.
+// Fields are written like parameters and methods like functions.
+type StructStatement struct {
+	Token   token.Token
+	Doc     string // text of the ## comments directly above the struct
+	Name    *Identifier
+	Fields  *ParameterList
+	Methods []*FuncStatement
+}
+
+func (ss *StructStatement) statementNode() {}
+
+func (ss *StructStatement) TokenLiteral() string {
+	return ss.Token.Literal
+}
+
+func (ss *StructStatement) Pos() token.Position {
+	return ss.Token.Pos
+}
+
+func (ss *StructStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString(ss.TokenLiteral())
+	out.WriteString(" ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	if len(ss.Fields.Identifiers) > 0 {
+		out.WriteString(ss.Fields.String())
+		out.WriteString("; ")
+	}
+	for _, method := range ss.Methods {
+		out.WriteString(method.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// FieldExpression is `left.field`, a field or method of a struct instance
+type FieldExpression struct {
+	Token token.Token // the '.'
+	Left  Expression
+	Field *Identifier
+}
+
+func (fe *FieldExpression) expressionNode() {}
+
+func (fe *FieldExpression) TokenLiteral() string {
+	return fe.Token.Literal
+}
+
+func (fe *FieldExpression) Pos() token.Position {
+	return fe.Token.Pos
+}
+
+func (fe *FieldExpression) String() string {
+	return fe.Left.String() + "." + fe.Field.String()
+}
+
+// ImportStatement is `import "path" as name;`. Without a name the module is named after its file.
+type ImportStatement struct {
+	Token token.Token
+	Path  *String
+	Name  *Identifier // nil if there is no as clause
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImportStatement) Pos() token.Position {
+	return is.Token.Pos
+}
+
+func (is *ImportStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString(is.TokenLiteral())
+	out.WriteString(" ")
+	out.WriteString(strconv.Quote(is.Path.Value))
+	if is.Name != nil {
+		out.WriteString(" as ")
+		out.WriteString(is.Name.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type ExpressionList struct {
 	Token       token.Token
 	Expressions []*Expression
@@ -356,6 +750,10 @@ func (el *ExpressionList) TokenLiteral() string {
 	return el.Token.Literal
 }
 
+func (el *ExpressionList) Pos() token.Position {
+	return el.Token.Pos
+}
+
 func (el *ExpressionList) String() string {
 	var out strings.Builder
 
@@ -371,9 +769,37 @@ func (el *ExpressionList) String() string {
 	return out.String()
 }
 
+type FunctionLiteral struct {
+	Token         token.Token
+	ParameterList *ParameterList
+	FuncBody      *BlockStatement
+}
+
+func (fl *FunctionLiteral) expressionNode() {}
+
+func (fl *FunctionLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+func (fl *FunctionLiteral) String() string {
+	var out strings.Builder
+
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(fl.ParameterList.String())
+	out.WriteString(") ")
+	out.WriteString(fl.FuncBody.String())
+
+	return out.String()
+}
+
 type CallExpression struct {
 	Token        token.Token
-	FunctionName *Identifier
+	Function     Expression // the callee, an identifier or any expression evaluating to a function
 	ArgumentList *ExpressionList
 }
 
@@ -383,10 +809,15 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+// Pos reports the position of the callee rather than the '(' token
+func (ce *CallExpression) Pos() token.Position {
+	return ce.Function.Pos()
+}
+
 func (ce *CallExpression) String() string {
 	var out strings.Builder
 
-	out.WriteString(ce.FunctionName.String())
+	out.WriteString(ce.Function.String())
 	out.WriteString("(")
 	out.WriteString(ce.ArgumentList.String())
 	out.WriteString(")")
@@ -407,22 +838,174 @@ func (fs *ForStatement) TokenLiteral() string {
 	return fs.Token.Literal
 }
 
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
 func (fs *ForStatement) String() string {
 	var out strings.Builder
 
 	out.WriteString(fs.TokenLiteral())
 	out.WriteString(" ")
-	out.WriteString(fs.Init.String())
+	if fs.Init != nil {
+		out.WriteString(fs.Init.String())
+	}
 	out.WriteString(";")
-	out.WriteString(fs.Condition.String())
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
 	out.WriteString(";")
-	out.WriteString(fs.Update.String())
+	if fs.Update != nil {
+		out.WriteString(fs.Update.String())
+	}
 	out.WriteString(" ")
 	out.WriteString(fs.ForBody.String())
 
 	return out.String()
 }
 
+type ForInStatement struct {
+	Token    token.Token
+	Key      *Identifier // the first of two loop variables, nil when there is only one
+	Value    *Identifier
+	Iterable Expression
+	ForBody  *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode() {}
+
+func (fs *ForInStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForInStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+func (fs *ForInStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString(fs.TokenLiteral())
+	out.WriteString(" ")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(" ")
+	out.WriteString(fs.ForBody.String())
+
+	return out.String()
+}
+
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+
+func (ws *WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+
+func (ws *WhileStatement) Pos() token.Position {
+	return ws.Token.Pos
+}
+
+func (ws *WhileStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString(ws.TokenLiteral())
+	out.WriteString(" ")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+type SwitchStatement struct {
+	Token   token.Token
+	Subject Expression // nil when every case is a condition of its own
+	Cases   []*CaseClause
+}
+
+func (ss *SwitchStatement) statementNode() {}
+
+func (ss *SwitchStatement) TokenLiteral() string {
+	return ss.Token.Literal
+}
+
+func (ss *SwitchStatement) Pos() token.Position {
+	return ss.Token.Pos
+}
+
+func (ss *SwitchStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString(ss.TokenLiteral())
+	if ss.Subject != nil {
+		out.WriteString(" ")
+		out.WriteString(ss.Subject.String())
+	}
+	out.WriteString(" { ")
+	for _, clause := range ss.Cases {
+		out.WriteString(clause.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// CaseClause is a case or the default of a switch statement
+type CaseClause struct {
+	Token       token.Token
+	Values      []Expression // empty for the default clause
+	Guard       Expression   // optional condition after `if`
+	Body        *BlockStatement
+	Fallthrough bool // the body ends with a fallthrough statement
+}
+
+func (cc *CaseClause) TokenLiteral() string {
+	return cc.Token.Literal
+}
+
+func (cc *CaseClause) Pos() token.Position {
+	return cc.Token.Pos
+}
+
+func (cc *CaseClause) String() string {
+	var out strings.Builder
+
+	out.WriteString(cc.TokenLiteral())
+	for idx, value := range cc.Values {
+		if idx > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString(" ")
+		out.WriteString(value.String())
+	}
+	if cc.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(cc.Guard.String())
+	}
+	out.WriteString(":")
+	for _, stmt := range cc.Body.Statements {
+		out.WriteString(" ")
+		out.WriteString(stmt.String())
+	}
+	if cc.Fallthrough {
+		out.WriteString(" fallthrough;")
+	}
+
+	return out.String()
+}
+
 type LoopControlStatement struct {
 	Token token.Token
 	Value string
@@ -434,6 +1017,10 @@ func (lc *LoopControlStatement) TokenLiteral() string {
 	return lc.Token.Literal
 }
 
+func (lc *LoopControlStatement) Pos() token.Position {
+	return lc.Token.Pos
+}
+
 func (lc *LoopControlStatement) String() string {
 	return lc.Token.Literal + ";"
 }
@@ -449,6 +1036,10 @@ func (l *List) TokenLiteral() string {
 	return l.Token.Literal
 }
 
+func (l *List) Pos() token.Position {
+	return l.Token.Pos
+}
+
 func (l *List) String() string {
 	var out strings.Builder
 
@@ -468,6 +1059,9 @@ func (ie *IndexExpression) expressionNode() {}
 func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *IndexExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
 func (ie *IndexExpression) String() string {
 	var out strings.Builder
 	out.WriteString("(")
@@ -477,3 +1071,65 @@ func (ie *IndexExpression) String() string {
 	out.WriteString("])")
 	return out.String()
 }
+
+type SliceExpression struct {
+	Token token.Token // The [ token
+	Left  Expression
+	Start Expression // nil when omitted
+	End   Expression // nil when omitted
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+func (se *SliceExpression) Pos() token.Position {
+	return se.Token.Pos
+}
+func (se *SliceExpression) String() string {
+	var out strings.Builder
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
+type MapLiteral struct {
+	Token  token.Token // the '{'
+	Keys   []Expression
+	Values []Expression // Values[i] belongs to Keys[i]
+}
+
+func (ml *MapLiteral) expressionNode() {}
+
+func (ml *MapLiteral) TokenLiteral() string {
+	return ml.Token.Literal
+}
+
+func (ml *MapLiteral) Pos() token.Position {
+	return ml.Token.Pos
+}
+
+func (ml *MapLiteral) String() string {
+	var out strings.Builder
+
+	out.WriteString("{")
+	for idx, key := range ml.Keys {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(key.String())
+		out.WriteString(": ")
+		out.WriteString(ml.Values[idx].String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
@@ -0,0 +1,159 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pandeykartikey/goto/token"
+)
+
+// dumpNode is the generic form of a Node used by Fprint and MarshalJSON. Fields keep the
+// declaration order of the struct they were read from.
+type dumpNode struct {
+	Type    string
+	Literal string
+	Pos     token.Position
+	Fields  []dumpField
+}
+
+type dumpField struct {
+	Name  string
+	Value interface{} // nil, *dumpNode, []interface{} or a basic value
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// toDump converts a value found in the AST into its generic form
+func toDump(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && v.Type().Implements(nodeType) {
+			return nodeToDump(v)
+		}
+		return toDump(v.Elem())
+	case reflect.Slice:
+		list := make([]interface{}, v.Len())
+		for idx := range list {
+			list[idx] = toDump(v.Index(idx))
+		}
+		return list
+	default:
+		return v.Interface()
+	}
+}
+
+func nodeToDump(v reflect.Value) *dumpNode {
+	node := v.Interface().(Node)
+	out := &dumpNode{Type: v.Elem().Type().Name(), Literal: node.TokenLiteral(), Pos: node.Pos()}
+
+	st := v.Elem()
+	for idx := 0; idx < st.NumField(); idx++ {
+		field := st.Type().Field(idx)
+		if field.Name == "Token" || field.PkgPath != "" {
+			continue
+		}
+		out.Fields = append(out.Fields, dumpField{Name: field.Name, Value: toDump(st.Field(idx))})
+	}
+
+	return out
+}
+
+// Fprint writes node to w as an indented tree, one node or field per line
+func Fprint(w io.Writer, node Node) error {
+	var out strings.Builder
+
+	writeDump(&out, toDump(reflect.ValueOf(node)), 0)
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func writeDump(out *strings.Builder, value interface{}, depth int) {
+	switch value := value.(type) {
+	case *dumpNode:
+		out.WriteString(value.Type)
+		if value.Literal != "" {
+			fmt.Fprintf(out, " %q", value.Literal)
+		}
+		if value.Pos.IsValid() {
+			fmt.Fprintf(out, " (%s)", value.Pos)
+		}
+		out.WriteString("\n")
+
+		for _, field := range value.Fields {
+			if list, ok := field.Value.([]interface{}); ok {
+				if len(list) == 0 {
+					fmt.Fprintf(out, "%s%s: []\n", indent(depth+1), field.Name)
+				}
+				for idx, item := range list {
+					fmt.Fprintf(out, "%s%s[%d]: ", indent(depth+1), field.Name, idx)
+					writeDump(out, item, depth+1)
+				}
+				continue
+			}
+			fmt.Fprintf(out, "%s%s: ", indent(depth+1), field.Name)
+			writeDump(out, field.Value, depth+1)
+		}
+	case nil:
+		out.WriteString("nil\n")
+	case string:
+		fmt.Fprintf(out, "%q\n", value)
+	default:
+		fmt.Fprintf(out, "%v\n", value)
+	}
+}
+
+func indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// MarshalJSON returns node as a JSON object holding its "type", "literal", "pos" and fields
+func MarshalJSON(node Node) ([]byte, error) {
+	return marshal(toDump(reflect.ValueOf(node)))
+}
+
+// like json.Marshal but leaves <, > and & unescaped, they are common in source code
+func marshal(v interface{}) ([]byte, error) {
+	var out bytes.Buffer
+
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+func (d *dumpNode) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+
+	header, err := marshal(struct {
+		Type    string `json:"type"`
+		Literal string `json:"literal"`
+		Pos     string `json:"pos"`
+	}{d.Type, d.Literal, d.Pos.String()})
+	if err != nil {
+		return nil, err
+	}
+	out.Write(header[:len(header)-1])
+
+	for _, field := range d.Fields {
+		value, err := marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, ",%q:", field.Name)
+		out.Write(value)
+	}
+	out.WriteString("}")
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package ast
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pandeykartikey/goto/token"
+)
+
+func testProgram() *Program {
+	pos := func(col int) token.Position {
+		return token.Position{Filename: "t.to", Line: 1, Column: col}
+	}
+	var left Expression = &Identifier{Token: token.Token{Type: token.IDENT, Literal: "a", Pos: pos(5)}, Value: "a"}
+	var right Expression = &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1", Pos: pos(9)}, Value: 1}
+
+	return &Program{
+		Statements: []Statement{
+			&Assignment{
+				Token: token.Token{Type: token.VAR, Literal: "var", Pos: pos(1)},
+				TargetList: &ExpressionList{
+					Token:       token.Token{Type: token.IDENT, Literal: "a", Pos: pos(5)},
+					Expressions: []*Expression{&left},
+				},
+				ValueList: &ExpressionList{
+					Token:       token.Token{Type: token.INT, Literal: "1", Pos: pos(9)},
+					Expressions: []*Expression{&right},
+				},
+			},
+		},
+	}
+}
+
+func TestFprint(t *testing.T) {
+	expected := `Program "var" (t.to:1:1)
+  Statements[0]: Assignment "var" (t.to:1:1)
+    TargetList: ExpressionList "a" (t.to:1:5)
+      Expressions[0]: Identifier "a" (t.to:1:5)
+        Value: "a"
+    ValueList: ExpressionList "1" (t.to:1:9)
+      Expressions[0]: IntegerLiteral "1" (t.to:1:9)
+        Value: 1
+    IsExpression: false
+`
+
+	var out strings.Builder
+	if err := Fprint(&out, testProgram()); err != nil {
+		t.Fatalf("Fprint returned error: %s", err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("Fprint output wrong. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	out, err := MarshalJSON(testProgram())
+	if err != nil {
+		t.Fatalf("MarshalJSON returned error: %s", err)
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(out, &tree); err != nil {
+		t.Fatalf("MarshalJSON output is not valid JSON: %s\n%s", err, out)
+	}
+
+	stmt := tree["Statements"].([]interface{})[0].(map[string]interface{})
+	if stmt["type"] != "Assignment" || stmt["literal"] != "var" || stmt["pos"] != "t.to:1:1" {
+		t.Errorf("statement header wrong. got=%v", stmt)
+	}
+
+	value := stmt["ValueList"].(map[string]interface{})["Expressions"].([]interface{})[0].(map[string]interface{})
+	if value["type"] != "IntegerLiteral" || value["Value"] != float64(1) {
+		t.Errorf("value wrong. got=%v", value)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pandeykartikey/goto/ast"
+	"github.com/pandeykartikey/goto/lexer"
+	"github.com/pandeykartikey/goto/parser"
+	"github.com/pandeykartikey/goto/token"
+)
+
+// subcommands take the arguments following their name
+var subcommands = map[string]func(args []string){
+	"tokens": tokensCommand,
+	"ast":    astCommand,
+}
+
+// opens the file named by the only argument, or stdin when it is missing or "-"
+func openInput(fs *flag.FlagSet) (string, io.ReadCloser, bool) {
+	switch {
+	case fs.NArg() > 1:
+		fs.Usage()
+		return "", nil, false
+	case fs.NArg() == 0 || fs.Arg(0) == "-":
+		return "<stdin>", os.Stdin, true
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Println(err.Error())
+		return "", nil, false
+	}
+
+	return fs.Arg(0), file, true
+}
+
+// tokensCommand prints the token stream of a script, one token per line
+func tokensCommand(args []string) {
+	fs := flag.NewFlagSet("tokens", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Usage:", os.Args[0], "tokens [FILE | -]")
+	}
+	fs.Parse(args)
+
+	filename, input, ok := openInput(fs)
+	if !ok {
+		return
+	}
+	defer input.Close()
+
+	l := lexer.NewFileReader(filename, input)
+	for {
+		tok := l.NextToken()
+		fmt.Printf("%-16s %-16s %q\n", tok.Pos, tok.Type, tok.Literal)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+}
+
+// astCommand prints the parsed program as an indented tree, or as JSON with -json
+func astCommand(args []string) {
+	fs := flag.NewFlagSet("ast", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the tree as JSON")
+	fs.Usage = func() {
+		fmt.Println("Usage:", os.Args[0], "ast [-json] [FILE | -]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	filename, input, ok := openInput(fs)
+	if !ok {
+		return
+	}
+	defer input.Close()
+
+	p := parser.New(lexer.NewFileReader(filename, input))
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		p.PrintParseErrors()
+		return
+	}
+
+	if !*asJSON {
+		ast.Fprint(os.Stdout, program)
+		return
+	}
+
+	out, err := ast.MarshalJSON(program)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Println(string(out))
+}
@@ -2,6 +2,9 @@ package eval
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/pandeykartikey/goto/object"
 )
@@ -10,15 +13,17 @@ var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return errorMessageToObject("wrong number of arguments. got=%d, want=1", len(args))
+				return errorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *object.List:
 				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Map:
+				return &object.Integer{Value: int64(len(arg.Pairs))}
 			default:
-				return errorMessageToObject("argument to `len` not supported, got %s", args[0].Type())
+				return errorOfKind(object.TYPE_ERROR, "argument to `len` not supported, got %s", args[0].Type())
 			}
 
 		},
@@ -26,10 +31,10 @@ var builtins = map[string]*object.Builtin{
 	"append": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return errorMessageToObject("wrong number of arguments. got=%d, want=2", len(args))
+				return errorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
 			}
 			if args[0].Type() != object.LIST_OBJ {
-				return errorMessageToObject("argument to `append` must be LIST, got %s", args[0].Type())
+				return errorOfKind(object.TYPE_ERROR, "argument to `append` must be LIST, got %s", args[0].Type())
 			}
 			list := args[0].(*object.List)
 			list.Value = append(list.Value, args[1])
@@ -44,4 +49,154 @@ var builtins = map[string]*object.Builtin{
 			return NULL
 		},
 	},
+	"int": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return errorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				// math.MaxInt64 rounds up to 2^63 as a float, the first value past the range
+				if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+					return errorOfKind(object.VALUE_ERROR, "could not convert %s to INTEGER", arg.Inspect())
+				}
+				return &object.Integer{Value: int64(arg.Value)}
+			case *object.Boolean:
+				if arg.Value {
+					return &object.Integer{Value: 1}
+				}
+				return &object.Integer{Value: 0}
+			case *object.String:
+				value, err := strconv.ParseInt(arg.Value, 10, 64)
+				if err != nil {
+					return errorOfKind(object.VALUE_ERROR, "could not convert %q to INTEGER", arg.Value)
+				}
+				return &object.Integer{Value: value}
+			default:
+				return errorOfKind(object.TYPE_ERROR, "argument to `int` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"float": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return errorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return &object.Float{Value: float64(arg.Value)}
+			case *object.Float:
+				return arg
+			case *object.String:
+				value, err := strconv.ParseFloat(arg.Value, 64)
+				if err != nil {
+					return errorOfKind(object.VALUE_ERROR, "could not convert %q to FLOAT", arg.Value)
+				}
+				return &object.Float{Value: value}
+			default:
+				return errorOfKind(object.TYPE_ERROR, "argument to `float` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"bytes": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return errorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return errorOfKind(object.TYPE_ERROR, "argument to `bytes` must be STRING, got %s", args[0].Type())
+			}
+			list := &object.List{Value: make([]object.Object, len(str.Value))}
+			for idx := 0; idx < len(str.Value); idx++ {
+				list.Value[idx] = &object.Integer{Value: int64(str.Value[idx])}
+			}
+			return list
+		},
+	},
+	"help": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return errorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Function:
+				return &object.String{Value: arg.Doc}
+			case *object.StructType:
+				return &object.String{Value: arg.Doc}
+			case *object.Builtin:
+				return &object.String{Value: arg.Inspect()}
+			default:
+				return errorOfKind(object.TYPE_ERROR, "argument to `help` must be a function, got %s", args[0].Type())
+			}
+		},
+	},
+	"keys": {
+		Fn: func(args ...object.Object) object.Object {
+			m, err := mapArgument("keys", 1, args)
+			if err != nil {
+				return err
+			}
+			list := &object.List{Value: make([]object.Object, len(m.Pairs))}
+			for idx, pair := range m.Pairs {
+				list.Value[idx] = pair.Key
+			}
+			return list
+		},
+	},
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			m, err := mapArgument("values", 1, args)
+			if err != nil {
+				return err
+			}
+			list := &object.List{Value: make([]object.Object, len(m.Pairs))}
+			for idx, pair := range m.Pairs {
+				list.Value[idx] = pair.Value
+			}
+			return list
+		},
+	},
+	"has": {
+		Fn: func(args ...object.Object) object.Object {
+			m, err := mapArgument("has", 2, args)
+			if err != nil {
+				return err
+			}
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return errorOfKind(object.TYPE_ERROR, "unusable as map key: %s", args[1].Type())
+			}
+			_, ok = m.Get(key)
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+	"delete": {
+		Fn: func(args ...object.Object) object.Object {
+			m, err := mapArgument("delete", 2, args)
+			if err != nil {
+				return err
+			}
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return errorOfKind(object.TYPE_ERROR, "unusable as map key: %s", args[1].Type())
+			}
+			m.Delete(key)
+			return NULL
+		},
+	},
+}
+
+// checks that a builtin got want arguments, the first of them a map
+func mapArgument(name string, want int, args []object.Object) (*object.Map, *object.Error) {
+	if len(args) != want {
+		return nil, errorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	m, ok := args[0].(*object.Map)
+	if !ok {
+		return nil, errorOfKind(object.TYPE_ERROR, "argument to `%s` must be MAP, got %s", name, args[0].Type())
+	}
+	return m, nil
 }
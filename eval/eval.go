@@ -3,9 +3,11 @@ package eval
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/pandeykartikey/goto/ast"
 	"github.com/pandeykartikey/goto/object"
+	"github.com/pandeykartikey/goto/token"
 )
 
 var (
@@ -17,8 +19,9 @@ var (
 
 func environmentwithBuiltins(env *object.Environment) *object.Environment {
 	for key, val := range builtins {
-		env.Create(key, val)
+		env.CreateConstant(key, val)
 	}
+	env.CreateConstant(errorStruct.Name, errorStruct)
 	return env
 }
 
@@ -38,7 +41,11 @@ func isError(obj object.Object) bool {
 }
 
 func errorMessageToObject(msg string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(msg, a...)}
+	return errorOfKind(object.RUNTIME_ERROR, msg, a...)
+}
+
+func errorOfKind(kind string, msg string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(msg, a...), Kind: kind}
 }
 
 // to evaluate a block of statements, nested to marked true when inside a nested block
@@ -67,7 +74,7 @@ func evalIdentifier(id *ast.Identifier, env *object.Environment) object.Object {
 	val, ok := env.Get(id.Value)
 
 	if !ok {
-		return errorMessageToObject("Identifier not found: %s", id.Value)
+		return errorOfKind(object.NAME_ERROR, "Identifier not found: %s", id.Value)
 	}
 	return val
 }
@@ -76,16 +83,16 @@ func evalNotOperator(obj object.Object) object.Object {
 	return nativeBoolToBooleanObject(!isTrue(obj))
 }
 
-// - Operator can only apply on integer value
+// - Operator can only apply on numeric values
 func evalNegateOperator(obj object.Object) object.Object {
-	intobj, ok := obj.(*object.Integer)
-	if !ok {
-		return errorMessageToObject("Unknown Operator: -%s", obj.Type())
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -obj.Value}
+	case *object.Float:
+		return &object.Float{Value: -obj.Value}
+	default:
+		return errorOfKind(object.TYPE_ERROR, "Unknown Operator: -%s", obj.Type())
 	}
-
-	intobj.Value = -intobj.Value
-
-	return intobj
 }
 
 func evalPrefixExpression(op string, right object.Object) object.Object {
@@ -95,7 +102,7 @@ func evalPrefixExpression(op string, right object.Object) object.Object {
 	case "-":
 		return evalNegateOperator(right)
 	default:
-		return errorMessageToObject("Unknown Operator: %s %s", op, right.Type())
+		return errorOfKind(object.TYPE_ERROR, "Unknown Operator: %s %s", op, right.Type())
 	}
 }
 
@@ -110,11 +117,89 @@ func evalInfixIntegerExpression(op string, left *object.Integer, right *object.I
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return errorOfKind(object.ARITHMETIC_ERROR, "Division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "%":
+		if rightVal == 0 {
+			return errorOfKind(object.ARITHMETIC_ERROR, "Division by zero")
+		}
 		return &object.Integer{Value: leftVal % rightVal}
 	case "**":
-		return &object.Integer{Value: int64(math.Pow(float64(leftVal), float64(rightVal)))}
+		if rightVal < 0 {
+			return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+		value, ok := integerPow(leftVal, rightVal)
+		if !ok {
+			return errorOfKind(object.ARITHMETIC_ERROR, "integer overflow in **")
+		}
+		return &object.Integer{Value: value}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	default:
+		return errorOfKind(object.TYPE_ERROR, "Unknown Operator: %s %s %s", left.Type(), op, right.Type())
+	}
+}
+
+// exponentiation by squaring, keeps integer powers exact where math.Pow would round. It reports
+// false when the result does not fit in an int64.
+func integerPow(base int64, exp int64) (int64, bool) {
+	result := int64(1)
+	ok := true
+	for {
+		if exp&1 == 1 {
+			if result, ok = multiplyExact(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp == 0 {
+			return result, true
+		}
+		// squared only while it is still needed, so the last square cannot overflow needlessly
+		if base, ok = multiplyExact(base, base); !ok {
+			return 0, false
+		}
+	}
+}
+
+// multiplies a and b, reporting whether the product fits in an int64
+func multiplyExact(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == math.MinInt64 && b == -1) {
+		return 0, false
+	}
+	return product, true
+}
+
+func evalInfixFloatExpression(op string, leftVal float64, rightVal float64) object.Object {
+	switch op {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -128,7 +213,19 @@ func evalInfixIntegerExpression(op string, left *object.Integer, right *object.I
 	case ">=":
 		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	default:
-		return errorMessageToObject("Unknown Operator: %s %s %s", left.Type(), op, right.Type())
+		return errorOfKind(object.TYPE_ERROR, "Unknown Operator: %s %s %s", object.FLOAT_OBJ, op, object.FLOAT_OBJ)
+	}
+}
+
+// returns the value of an integer or float object as float64, ok is false for every other type
+func toFloat(obj object.Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value), true
+	case *object.Float:
+		return obj.Value, true
+	default:
+		return 0, false
 	}
 }
 
@@ -141,7 +238,7 @@ func evalInfixBooleanExpression(op string, left *object.Boolean, right *object.B
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return errorMessageToObject("Unknown Operator: %s %s %s", left.Type(), op, right.Type())
+		return errorOfKind(object.TYPE_ERROR, "Unknown Operator: %s %s %s", left.Type(), op, right.Type())
 	}
 }
 
@@ -156,7 +253,7 @@ func evalInfixStringExpression(op string, left *object.String, right *object.Str
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return errorMessageToObject("Unknown Operator: %s %s %s", left.Type(), op, right.Type())
+		return errorOfKind(object.TYPE_ERROR, "Unknown Operator: %s %s %s", left.Type(), op, right.Type())
 	}
 }
 
@@ -168,8 +265,16 @@ func evalInfixExpression(op string, left object.Object, right object.Object) obj
 		return nativeBoolToBooleanObject(isTrue(left) || isTrue(right))
 	}
 
+	if left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ {
+		leftVal, leftOk := toFloat(left)
+		rightVal, rightOk := toFloat(right)
+		if leftOk && rightOk {
+			return evalInfixFloatExpression(op, leftVal, rightVal)
+		}
+	}
+
 	if left.Type() != right.Type() {
-		return errorMessageToObject("Type Mismatch: %s %s %s", left.Type(), op, right.Type())
+		return errorOfKind(object.TYPE_ERROR, "Type Mismatch: %s %s %s", left.Type(), op, right.Type())
 	}
 
 	switch left.(type) {
@@ -180,11 +285,11 @@ func evalInfixExpression(op string, left object.Object, right object.Object) obj
 	case *object.String:
 		return evalInfixStringExpression(op, left.(*object.String), right.(*object.String))
 	default:
-		return errorMessageToObject("Unknown Type %s %s", left.Type(), right.Type())
+		return errorOfKind(object.TYPE_ERROR, "Unknown Type %s %s", left.Type(), right.Type())
 	}
 }
 
-// NULL,0,"" is false and all other values are true
+// NULL,0,0.0,"" is false and all other values are true
 func isTrue(obj object.Object) bool {
 	switch obj {
 	case TRUE:
@@ -200,6 +305,11 @@ func isTrue(obj object.Object) bool {
 				return false
 			}
 			return true
+		case *object.Float:
+			if obj.(*object.Float).Value == 0 {
+				return false
+			}
+			return true
 		case *object.String:
 			if obj.(*object.String).Value == "" {
 				return false
@@ -234,20 +344,185 @@ func evalArrayIndexExpression(list *object.List, idx int64) object.Object {
 	max := int64(len(list.Value) - 1)
 
 	if idx < 0 || idx > max {
-		return errorMessageToObject("List index out of range")
+		return errorOfKind(object.INDEX_ERROR, "List index out of range")
 	}
 
 	return list.Value[idx]
 }
 
+// strings are indexed by code point, use the bytes builtin for byte level access
 func evalStringIndexExpression(str *object.String, idx int64) object.Object {
-	max := int64(len(str.Value) - 1)
+	runes := []rune(str.Value)
+	max := int64(len(runes) - 1)
 
 	if idx < 0 || idx > max {
-		return errorMessageToObject("String index out of range")
+		return errorOfKind(object.INDEX_ERROR, "String index out of range")
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// converts the bounds of a slice over length elements into offsets. Negative bounds count from
+// the end and bounds outside the sequence are clamped to it.
+func sliceBounds(start, end object.Object, length int64) (int64, int64, *object.Error) {
+	bounds := [2]int64{0, length}
+
+	for idx, bound := range []object.Object{start, end} {
+		if bound == nil {
+			continue
+		}
+		integer, ok := bound.(*object.Integer)
+		if !ok {
+			return 0, 0, errorOfKind(object.TYPE_ERROR, "slice bounds must be INTEGER, got %s", bound.Type())
+		}
+
+		value := integer.Value
+		if value < 0 {
+			value += length
+		}
+		if value < 0 {
+			value = 0
+		} else if value > length {
+			value = length
+		}
+		bounds[idx] = value
+	}
+
+	if bounds[0] > bounds[1] {
+		bounds[0] = bounds[1]
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// returns a copy of the elements or characters of left between start and end, either of which
+// may be nil
+func evalSliceExpression(left, start, end object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.List:
+		low, high, err := sliceBounds(start, end, int64(len(left.Value)))
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, high-low)
+		copy(elements, left.Value[low:high])
+		return &object.List{Value: elements}
+	case *object.String:
+		runes := []rune(left.Value)
+		low, high, err := sliceBounds(start, end, int64(len(runes)))
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: string(runes[low:high])}
+	default:
+		return errorOfKind(object.TYPE_ERROR, "slice operator not supported: %s", left.Type())
+	}
+}
+
+func evalMapIndexExpression(m *object.Map, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return errorOfKind(object.TYPE_ERROR, "unusable as map key: %s", index.Type())
+	}
+
+	value, ok := m.Get(key)
+	if !ok {
+		return errorOfKind(object.KEY_ERROR, "Key not found: %s", index.Inspect())
+	}
+
+	return value
+}
+
+func evalMapLiteral(node *ast.MapLiteral, env *object.Environment) object.Object {
+	m := object.NewMap()
+
+	for idx, keyNode := range node.Keys {
+		keyObj := evalProgram(keyNode, env)
+		if isError(keyObj) {
+			return keyObj
+		}
+
+		key, ok := keyObj.(object.Hashable)
+		if !ok {
+			return errorOfKind(object.TYPE_ERROR, "unusable as map key: %s", keyObj.Type())
+		}
+
+		value := evalProgram(node.Values[idx], env)
+		if isError(value) {
+			return value
+		}
+
+		m.Set(key, value)
+	}
+
+	return m
+}
+
+// returns the field called name of left, or the method of that name bound to left. For a module
+// it returns the top-level name of the module.
+func evalFieldExpression(left object.Object, name string) object.Object {
+	switch left := left.(type) {
+	case *object.Instance:
+		if value, ok := left.Fields[name]; ok {
+			return value
+		}
+		if method, ok := left.Struct.Methods[name]; ok {
+			return bindMethod(left, method)
+		}
+		return errorOfKind(object.NAME_ERROR, "%s has no field or method %s", left.Struct.Name, name)
+	case *object.Module:
+		if value, ok := left.Env.GetLocal(name); ok {
+			return value
+		}
+		return errorOfKind(object.NAME_ERROR, "module %s has no member %s", left.Name, name)
+	default:
+		return errorOfKind(object.TYPE_ERROR, "field access not supported: %s", left.Type())
+	}
+}
+
+// returns method with self bound to inst in a scope between the method and the scope of the struct
+func bindMethod(inst *object.Instance, method *object.Function) *object.Function {
+	env := object.ExtendEnv(method.Env)
+	env.Create("self", inst)
+
+	return &object.Function{Doc: method.Doc, ParameterList: method.ParameterList, FuncBody: method.FuncBody, Env: env}
+}
+
+func evalFieldAssignment(left object.Object, name string, value object.Object) object.Object {
+	inst, ok := left.(*object.Instance)
+	if !ok {
+		return errorOfKind(object.TYPE_ERROR, "field assignment not supported: %s", left.Type())
+	}
+
+	if _, ok := inst.Fields[name]; !ok {
+		return errorOfKind(object.NAME_ERROR, "%s has no field %s", inst.Struct.Name, name)
+	}
+	inst.Fields[name] = value
+
+	return nil
+}
+
+// stores value at left[index], left being a list or a map
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	switch {
+	case left.Type() == object.LIST_OBJ && index.Type() == object.INTEGER_OBJ:
+		list := left.(*object.List)
+		idx := index.(*object.Integer).Value
+		if idx < 0 || idx > int64(len(list.Value)-1) {
+			return errorOfKind(object.INDEX_ERROR, "List index out of range")
+		}
+		list.Value[idx] = value
+	case left.Type() == object.MAP_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return errorOfKind(object.TYPE_ERROR, "unusable as map key: %s", index.Type())
+		}
+		left.(*object.Map).Set(key, value)
+	default:
+		return errorOfKind(object.TYPE_ERROR, "index assignment not supported: %s", left.Type())
 	}
 
-	return &object.String{Value: string(str.Value[idx])}
+	return nil
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
@@ -256,9 +531,101 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalArrayIndexExpression(left.(*object.List), index.(*object.Integer).Value)
 	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalStringIndexExpression(left.(*object.String), index.(*object.Integer).Value)
+	case left.Type() == object.MAP_OBJ:
+		return evalMapIndexExpression(left.(*object.Map), index)
 	default:
-		return errorMessageToObject("index operator not supported: %s", left.Type())
+		return errorOfKind(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
+	}
+}
+
+// an assignment target whose container and index have already been evaluated
+type resolvedTarget struct {
+	node  ast.Expression
+	left  object.Object // the list, map or instance for index and field targets
+	index object.Object
+}
+
+// evaluates the operands of target, so that every target of a multiple assignment
+// refers to what it did before any of them is assigned
+func resolveTarget(target ast.Expression, env *object.Environment) (*resolvedTarget, object.Object) {
+	resolved := &resolvedTarget{node: target}
+
+	switch target := target.(type) {
+	case *ast.Identifier:
+	case *ast.IndexExpression:
+		resolved.left = evalProgram(target.Left, env)
+		if isError(resolved.left) {
+			return nil, resolved.left
+		}
+		resolved.index = evalProgram(target.Index, env)
+		if isError(resolved.index) {
+			return nil, resolved.index
+		}
+	case *ast.FieldExpression:
+		resolved.left = evalProgram(target.Left, env)
+		if isError(resolved.left) {
+			return nil, resolved.left
+		}
+	default:
+		return nil, errorMessageToObject("cannot assign to %s", target.String())
+	}
+
+	return resolved, nil
+}
+
+// stores value in the variable or element target refers to. For compound assignments op is the
+// operator combining the current value with value, otherwise it is empty.
+func assignTarget(target *resolvedTarget, op string, value object.Object, env *object.Environment) object.Object {
+	switch node := target.node.(type) {
+	case *ast.Identifier:
+		if op != "" {
+			current := evalIdentifier(node, env)
+			if isError(current) {
+				return current
+			}
+			if value = evalInfixExpression(op, current, value); isError(value) {
+				return value
+			}
+		}
+		if env.IsConstant(node.Value) {
+			return errorMessageToObject("cannot assign to constant %s", node.Value)
+		}
+		if _, ok := env.Update(node.Value, value); !ok {
+			return errorOfKind(object.NAME_ERROR, "An identifier does not exists with that name")
+		}
+	case *ast.IndexExpression:
+		if op != "" {
+			current := evalIndexExpression(target.left, target.index)
+			if isError(current) {
+				current.(*object.Error).Pos = node.Pos()
+				return current
+			}
+			if value = evalInfixExpression(op, current, value); isError(value) {
+				return value
+			}
+		}
+		if out := evalIndexAssignment(target.left, target.index, value); isError(out) {
+			out.(*object.Error).Pos = node.Pos()
+			return out
+		}
+	case *ast.FieldExpression:
+		if op != "" {
+			current := evalFieldExpression(target.left, node.Field.Value)
+			if isError(current) {
+				current.(*object.Error).Pos = node.Pos()
+				return current
+			}
+			if value = evalInfixExpression(op, current, value); isError(value) {
+				return value
+			}
+		}
+		if out := evalFieldAssignment(target.left, node.Field.Value, value); isError(out) {
+			out.(*object.Error).Pos = node.Pos()
+			return out
+		}
 	}
+
+	return nil
 }
 
 func evalAssignment(assignStmt *ast.Assignment, env *object.Environment) object.Object {
@@ -279,30 +646,75 @@ func evalAssignment(assignStmt *ast.Assignment, env *object.Environment) object.
 		}
 	}
 
-	for idx, ident := range assignStmt.NameList.Identifiers {
-		switch assignStmt.TokenLiteral() {
-		case "var":
+	switch assignStmt.TokenLiteral() {
+	case "var", "const":
+		for idx, target := range assignStmt.TargetList.Expressions {
+			ident := (*target).(*ast.Identifier)
+			if env.IsConstant(ident.Value) {
+				return errorMessageToObject("cannot redeclare constant %s", ident.Value)
+			}
+
+			var value object.Object = DEFAULT_INT
 			if valueList != nil {
-				if _, ok = env.Create(ident.Value, valueList.Value[idx]); !ok {
-					return errorMessageToObject("An identifier already exists with that name")
-				}
-			} else {
-				if _, ok = env.Create(ident.Value, DEFAULT_INT); !ok {
-					return errorMessageToObject("An identifier already exists with that name")
-				}
+				value = valueList.Value[idx]
+			}
+
+			create := env.Create
+			if assignStmt.Token.Type == token.CONST {
+				create = env.CreateConstant
 			}
-		case "=":
-			if _, ok = env.Update(ident.Value, valueList.Value[idx]); !ok {
-				return errorMessageToObject("An identifier does not exists with that name")
+			if _, ok = create(ident.Value, value); !ok {
+				return errorMessageToObject("An identifier already exists with that name")
 			}
-		default:
+		}
+		return nil
+	}
+
+	var op string
+	if assignStmt.TokenLiteral() != "=" {
+		compound, ok := token.AssignmentOperators[assignStmt.Token.Type]
+		if !ok {
 			return errorMessageToObject("Unexpected Error encountered")
 		}
+		op = string(compound)
+	}
+
+	targets := make([]*resolvedTarget, len(assignStmt.TargetList.Expressions))
+	for idx, target := range assignStmt.TargetList.Expressions {
+		resolved, err := resolveTarget(*target, env)
+		if err != nil {
+			return err
+		}
+		targets[idx] = resolved
+	}
+
+	for idx, target := range targets {
+		var value object.Object = &object.Integer{Value: 1} // ++ and --
+		if valueList != nil {
+			value = valueList.Value[idx]
+		}
+		if out := assignTarget(target, op, value, env); isError(out) {
+			return out
+		}
 	}
 
 	return nil
 }
 
+func evalInterpolatedString(str *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for _, part := range str.Parts {
+		obj := evalProgram(part, env)
+		if isError(obj) {
+			return obj
+		}
+		out.WriteString(obj.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
 func evalIfStatement(ifStmt *ast.IfStatement, env *object.Environment) object.Object {
 	cond := evalProgram(ifStmt.Condition, env)
 
@@ -321,51 +733,324 @@ func evalIfStatement(ifStmt *ast.IfStatement, env *object.Environment) object.Ob
 	return NULL
 }
 
-func evalForStatement(forStmt *ast.ForStatement, env *object.Environment) object.Object {
-	out := evalAssignment(forStmt.Init, env)
-	if isError(out) {
-		return out
+// evaluates one iteration of a loop body in env, which should be a fresh scope. It reports whether
+// the loop has to stop and, if it stops because of an error or a return, the value to pass on.
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	out := evalStatements(body.Statements, env, false)
+
+	switch out := out.(type) {
+	case *object.LoopControl:
+		return nil, out.Value == "break"
+	case *object.Error, *object.ReturnValue:
+		return out, true
 	}
 
-	extendedEnv := object.ExtendEnv(env)
+	return nil, false
+}
+
+func evalForStatement(forStmt *ast.ForStatement, env *object.Environment) object.Object {
+	// variables declared by the init clause stay visible after the loop
+	if forStmt.Init != nil {
+		if out := evalAssignment(forStmt.Init, env); isError(out) {
+			return out
+		}
+	}
 
-forLoop:
 	for {
+		if forStmt.Condition != nil {
+			cond := evalProgram(forStmt.Condition, env)
+			if isError(cond) {
+				return cond
+			}
+			if !isTrue(cond) {
+				break
+			}
+		}
 
-		cond := evalProgram(forStmt.Condition, extendedEnv)
+		if out, stop := evalLoopBody(forStmt.ForBody, object.ExtendEnv(env)); stop {
+			return out
+		}
+
+		if forStmt.Update != nil {
+			if out := evalProgram(forStmt.Update, env); isError(out) {
+				return out
+			}
+		}
+	}
+
+	return nil
+}
+
+func evalWhileStatement(whileStmt *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		cond := evalProgram(whileStmt.Condition, env)
 		if isError(cond) {
 			return cond
 		}
 		if !isTrue(cond) {
+			return nil
+		}
+
+		if out, stop := evalLoopBody(whileStmt.Body, object.ExtendEnv(env)); stop {
+			return out
+		}
+	}
+}
+
+// evaluates the first clause of a switch that matches, and the ones it falls through to. A break
+// ends the switch, while continue is passed on to the enclosing loop.
+func evalSwitchStatement(switchStmt *ast.SwitchStatement, env *object.Environment) object.Object {
+	var subject object.Object
+	if switchStmt.Subject != nil {
+		if subject = evalProgram(switchStmt.Subject, env); isError(subject) {
+			return subject
+		}
+	}
+
+	matched := -1
+	for idx, clause := range switchStmt.Cases {
+		if len(clause.Values) == 0 { // default
+			if matched == -1 {
+				matched = idx
+			}
+			continue
+		}
+
+		ok, err := caseMatches(clause, subject, env)
+		if err != nil {
+			return err
+		}
+		if ok {
+			matched = idx
 			break
 		}
+	}
 
-		out = evalStatements(forStmt.ForBody.Statements, extendedEnv, false)
+	if matched == -1 {
+		return nil
+	}
+
+	for _, clause := range switchStmt.Cases[matched:] {
+		out := evalStatements(clause.Body.Statements, object.ExtendEnv(env), false)
 
-		switch out.(type) {
+		switch out := out.(type) {
 		case *object.LoopControl:
-			if out.Inspect() == "break" {
-				break forLoop
+			if out.Value == "break" {
+				return nil
 			}
+			return out
 		case *object.Error, *object.ReturnValue:
 			return out
 		}
 
-		out = evalProgram(forStmt.Update, extendedEnv)
-		if isError(out) {
-			return out
+		if !clause.Fallthrough {
+			break
+		}
+	}
+
+	return nil
+}
+
+// reports whether one of the values of clause equals subject, or without a subject whether one of
+// them is true, and whether the guard holds
+func caseMatches(clause *ast.CaseClause, subject object.Object, env *object.Environment) (bool, object.Object) {
+	matches := false
+
+	for _, valueNode := range clause.Values {
+		value := evalProgram(valueNode, env)
+		if isError(value) {
+			return false, value
+		}
+
+		if subject != nil {
+			if value = evalInfixExpression("==", subject, value); isError(value) {
+				value.(*object.Error).Pos = valueNode.Pos()
+				return false, value
+			}
+		}
+
+		if isTrue(value) {
+			matches = true
+			break
+		}
+	}
+
+	if !matches || clause.Guard == nil {
+		return matches, nil
+	}
+
+	guard := evalProgram(clause.Guard, env)
+	if isError(guard) {
+		return false, guard
+	}
+
+	return isTrue(guard), nil
+}
+
+// evaluates a for-in loop over the elements of a list, the characters of a string or the keys of
+// a map. With two loop variables the first one is bound to the index or key.
+func evalForInStatement(forStmt *ast.ForInStatement, env *object.Environment) object.Object {
+	if err := checkNotConstant(env, forStmt.Key, forStmt.Value); err != nil {
+		return err
+	}
+
+	iterable := evalProgram(forStmt.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	iterate := func(key, value object.Object) (object.Object, bool) {
+		iterEnv := object.ExtendEnv(env)
+		if forStmt.Key != nil {
+			iterEnv.Create(forStmt.Key.Value, key)
+			iterEnv.Create(forStmt.Value.Value, value)
+		} else if iterable.Type() == object.MAP_OBJ {
+			iterEnv.Create(forStmt.Value.Value, key)
+		} else {
+			iterEnv.Create(forStmt.Value.Value, value)
+		}
+		return evalLoopBody(forStmt.ForBody, iterEnv)
+	}
+
+	switch iterable := iterable.(type) {
+	case *object.List:
+		for idx, element := range iterable.Value {
+			if out, stop := iterate(&object.Integer{Value: int64(idx)}, element); stop {
+				return out
+			}
+		}
+	case *object.String:
+		for idx, ch := range []rune(iterable.Value) {
+			if out, stop := iterate(&object.Integer{Value: int64(idx)}, &object.String{Value: string(ch)}); stop {
+				return out
+			}
+		}
+	case *object.Map:
+		pairs := make([]*object.MapPair, len(iterable.Pairs))
+		copy(pairs, iterable.Pairs)
+
+		for _, pair := range pairs {
+			value, ok := iterable.Get(pair.Key.(object.Hashable))
+			if !ok { // deleted by an earlier iteration
+				continue
+			}
+			if out, stop := iterate(pair.Key, value); stop {
+				return out
+			}
+		}
+	default:
+		return errorOfKind(object.TYPE_ERROR, "cannot iterate over %s", iterable.Type())
+	}
+
+	return nil
+}
+
+func evalStructStatement(structStmt *ast.StructStatement, env *object.Environment) object.Object {
+	structObj := &object.StructType{
+		Name:    structStmt.Name.Value,
+		Doc:     structStmt.Doc,
+		Fields:  structStmt.Fields,
+		Methods: make(map[string]*object.Function),
+		Env:     env,
+	}
+
+	for _, method := range structStmt.Methods {
+		structObj.Methods[method.Name.Value] = &object.Function{
+			Doc:           method.Doc,
+			ParameterList: method.ParameterList,
+			FuncBody:      method.FuncBody,
+			Env:           env,
 		}
 	}
 
+	if env.IsConstant(structStmt.Name.Value) {
+		return errorMessageToObject("cannot redeclare constant %s", structStmt.Name.Value)
+	}
+	if _, ok := env.Create(structStmt.Name.Value, structObj); !ok {
+		return errorMessageToObject("A struct already exists with that name")
+	}
+
 	return nil
 }
 
+// errorStruct is the type of the value a catch block receives. Scripts can create it to throw an
+// error of their own kind, `throw Error("bad input", kind: "ValueError");`
+var errorStruct = &object.StructType{
+	Name: "Error",
+	Fields: &ast.ParameterList{
+		Identifiers: []*ast.Identifier{{Value: "message"}, {Value: "kind"}},
+		Defaults: []ast.Expression{
+			nil,
+			&ast.String{Token: token.Token{Type: token.STRING, Literal: object.GENERIC_ERROR}, Value: object.GENERIC_ERROR},
+		},
+	},
+	Methods: make(map[string]*object.Function),
+	Env:     object.NewEnvironment(),
+}
+
+func errorToInstance(err *object.Error) *object.Instance {
+	return &object.Instance{Struct: errorStruct, Fields: map[string]object.Object{
+		"message": &object.String{Value: err.Message},
+		"kind":    &object.String{Value: err.Kind},
+	}}
+}
+
+// returns the error raised by `throw value`. Throwing an Error, such as a caught one, keeps its
+// kind, any other value becomes the message of a GENERIC_ERROR.
+func thrownError(value object.Object) *object.Error {
+	if inst, ok := value.(*object.Instance); ok && inst.Struct == errorStruct {
+		return errorOfKind(inst.Fields["kind"].Inspect(), "%s", inst.Fields["message"].Inspect())
+	}
+
+	return errorOfKind(object.GENERIC_ERROR, "%s", value.Inspect())
+}
+
+// returns an error if one of names refers to a constant, which binding the name in a new scope
+// would hide. Missing names are skipped.
+func checkNotConstant(env *object.Environment, names ...*ast.Identifier) object.Object {
+	for _, name := range names {
+		if name != nil && env.IsConstant(name.Value) {
+			return errorMessageToObject("cannot redeclare constant %s", name.Value)
+		}
+	}
+	return nil
+}
+
+func evalTryStatement(stmt *ast.TryStatement, env *object.Environment) object.Object {
+	out := evalProgram(stmt.Body, env)
+
+	if err, ok := out.(*object.Error); ok && stmt.Catch != nil {
+		if out = checkNotConstant(env, stmt.CatchName); out == nil {
+			catchEnv := object.ExtendEnv(env)
+			if stmt.CatchName != nil {
+				catchEnv.Create(stmt.CatchName.Value, errorToInstance(err))
+			}
+			out = evalStatements(stmt.Catch.Statements, catchEnv, false)
+		}
+	}
+
+	if stmt.Finally != nil {
+		// a finally block that returns, breaks or fails replaces the outcome of the rest
+		switch finally := evalProgram(stmt.Finally, env); finally.(type) {
+		case *object.Error, *object.ReturnValue, *object.LoopControl:
+			return finally
+		}
+	}
+
+	return out
+}
+
 func evalFuncStatement(funcStmt *ast.FuncStatement, env *object.Environment) object.Object {
 	funcObj := &object.Function{
+		Doc:           funcStmt.Doc,
 		ParameterList: funcStmt.ParameterList,
 		FuncBody:      funcStmt.FuncBody,
+		Env:           env,
 	}
 
+	if env.IsConstant(funcStmt.Name.Value) {
+		return errorMessageToObject("cannot redeclare constant %s", funcStmt.Name.Value)
+	}
 	if _, ok := env.Create(funcStmt.Name.Value, funcObj); !ok {
 		return errorMessageToObject("A function already exists with that name")
 	}
@@ -373,69 +1058,264 @@ func evalFuncStatement(funcStmt *ast.FuncStatement, env *object.Environment) obj
 	return nil
 }
 
-func addArgumentsToEnvironment(fn *object.Function, objList *object.List, env *object.Environment) *object.Environment {
+// an argument passed by name, already evaluated at the call site
+type keywordArgument struct {
+	Name  string
+	Value object.Object
+}
+
+// binds the arguments of a call to params in a scope extending env.
+// Positional arguments are bound first, extra ones go to the variadic parameter, then keyword
+// arguments. Parameters still unbound get their default value, evaluated in the new scope so it
+// can refer to the parameters before it.
+func addArgumentsToEnvironment(name string, params *ast.ParameterList, args *object.List, kwargs []keywordArgument, env *object.Environment) (*object.Environment, object.Object) {
 	extendedEnv := object.ExtendEnv(env)
 
-	for idx, param := range fn.ParameterList.Identifiers {
-		extendedEnv.Create(param.Value, objList.Value[idx])
+	if params == nil {
+		params = &ast.ParameterList{}
 	}
 
-	return extendedEnv
-}
+	if len(args.Value) > len(params.Identifiers) && params.Rest == nil {
+		return nil, errorOfKind(object.ARGUMENT_ERROR, "Number of arguments passed donot match %s's number of parameters", name)
+	}
 
-func evalCallExpression(name string, obj object.Object, env *object.Environment) object.Object {
-	args, ok := obj.(*object.List)
-	if !ok {
-		return errorMessageToObject("Unknown Operator: %s", obj.Type())
+	bound := make(map[string]bool)
+	for idx, param := range params.Identifiers {
+		if idx < len(args.Value) {
+			extendedEnv.Create(param.Value, args.Value[idx])
+			bound[param.Value] = true
+		}
 	}
 
-	fn, ok := env.Get(name)
-	if !ok {
-		return errorMessageToObject("Function not found: %s", name)
+	if params.Rest != nil {
+		rest := &object.List{}
+		if len(args.Value) > len(params.Identifiers) {
+			rest.Value = append(rest.Value, args.Value[len(params.Identifiers):]...)
+		}
+		extendedEnv.Create(params.Rest.Value, rest)
 	}
 
-	switch fn.(type) {
-	case *object.Function:
-		fnObj := fn.(*object.Function)
-		var extendedEnv *object.Environment
-		if fnObj.ParameterList != nil {
-			if len(fnObj.ParameterList.Identifiers) == len(args.Value) {
-				extendedEnv = addArgumentsToEnvironment(fnObj, args, env)
+	for _, kwarg := range kwargs {
+		known := false
+		for _, param := range params.Identifiers {
+			known = known || param.Value == kwarg.Name
+		}
+		if !known {
+			return nil, errorOfKind(object.ARGUMENT_ERROR, "%s got an unexpected keyword argument %s", name, kwarg.Name)
+		}
+		if bound[kwarg.Name] {
+			return nil, errorOfKind(object.ARGUMENT_ERROR, "%s got multiple values for argument %s", name, kwarg.Name)
+		}
+		extendedEnv.Create(kwarg.Name, kwarg.Value)
+		bound[kwarg.Name] = true
+	}
+
+	for idx, param := range params.Identifiers {
+		if bound[param.Value] {
+			continue
+		}
+		if params.Defaults[idx] == nil {
+			return nil, errorOfKind(object.ARGUMENT_ERROR, "%s missing argument %s", name, param.Value)
+		}
+
+		value := evalProgram(params.Defaults[idx], extendedEnv)
+		if isError(value) {
+			return nil, value
+		}
+		extendedEnv.Create(param.Value, value)
+	}
+
+	return extendedEnv, nil
+}
+
+// a call whose callee and arguments have been evaluated but which has not been made yet
+type preparedCall struct {
+	name   string // the callee as written
+	fn     object.Object
+	args   *object.List
+	kwargs []keywordArgument
+	pos    token.Position
+}
+
+func prepareCall(call *ast.CallExpression, env *object.Environment) (*preparedCall, object.Object) {
+	var fn object.Object
+
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		if fn, ok = env.Get(ident.Value); !ok {
+			return nil, errorOfKind(object.NAME_ERROR, "Function not found: %s", ident.Value)
+		}
+	} else {
+		fn = evalProgram(call.Function, env)
+		if isError(fn) {
+			return nil, fn
+		}
+	}
+
+	args := &object.List{}
+	var kwargs []keywordArgument
+
+	if call.ArgumentList != nil {
+		for _, expr := range call.ArgumentList.Expressions {
+			var value object.Object
+
+			kwarg, isKeyword := (*expr).(*ast.KeywordArgument)
+			if isKeyword {
+				value = evalProgram(kwarg.Value, env)
 			} else {
-				return errorMessageToObject("Number of arguments passed donot match %s's number of parameters", name)
+				value = evalProgram(*expr, env)
 			}
-		} else {
-			if len(args.Value) == 0 {
-				extendedEnv = env
+			if isError(value) {
+				return nil, value
+			}
+
+			if isKeyword {
+				kwargs = append(kwargs, keywordArgument{Name: kwarg.Name.Value, Value: value})
 			} else {
-				return errorMessageToObject("Number of arguments passed donot match %s's number of parameters", name)
+				args.Value = append(args.Value, value)
+			}
+		}
+	}
+
+	return &preparedCall{name: call.Function.String(), fn: fn, args: args, kwargs: kwargs, pos: call.Pos()}, nil
+}
+
+func evalCallExpression(call *ast.CallExpression, env *object.Environment) object.Object {
+	prepared, err := prepareCall(call, env)
+	if err != nil {
+		return err
+	}
+
+	return applyFunction(prepared.name, prepared.fn, prepared.args, prepared.kwargs)
+}
+
+func evalDeferStatement(stmt *ast.DeferStatement, env *object.Environment) object.Object {
+	frame := env.Frame()
+	if frame == nil {
+		return errorMessageToObject("defer used outside function")
+	}
+
+	prepared, err := prepareCall(stmt.Call, env)
+	if err != nil {
+		return err
+	}
+
+	frame.Deferred = append(frame.Deferred, func() object.Object {
+		result := applyFunction(prepared.name, prepared.fn, prepared.args, prepared.kwargs)
+		if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+			err.Pos = prepared.pos
+		}
+		return result
+	})
+
+	return nil
+}
+
+// makes the calls deferred during the returning function call, the last registered first. An
+// error raised by one of them replaces out.
+func runDeferred(frame *object.Frame, out object.Object) object.Object {
+	for idx := len(frame.Deferred) - 1; idx >= 0; idx-- {
+		if result := frame.Deferred[idx](); isError(result) {
+			out = result
+		}
+	}
+
+	return out
+}
+
+// calls fn with args and kwargs. name is the callee as written, used in error messages
+func applyFunction(name string, fn object.Object, args *object.List, kwargs []keywordArgument) object.Object {
+	switch fn := fn.(type) {
+	case *object.Function:
+		// parameters cannot hide a constant, any more than a variable can
+		if params := fn.ParameterList; params != nil {
+			if err := checkNotConstant(fn.Env, append([]*ast.Identifier{params.Rest}, params.Identifiers...)...); err != nil {
+				return err
 			}
 		}
 
-		return evalStatements(fnObj.FuncBody.Statements, extendedEnv, true)
+		// the body sees the scope the function was defined in, not the caller's
+		extendedEnv, err := addArgumentsToEnvironment(name, fn.ParameterList, args, kwargs, fn.Env)
+		if err != nil {
+			return err
+		}
+
+		frame := &object.Frame{}
+		extendedEnv.SetFrame(frame)
+		out := evalStatements(fn.FuncBody.Statements, extendedEnv, true)
+		if out == nil { // the body produced no value
+			out = NULL
+		}
+
+		return runDeferred(frame, out)
+
+	case *object.StructType:
+		// fields are bound like parameters, so they can be passed by position or by name
+		fieldEnv, err := addArgumentsToEnvironment(name, fn.Fields, args, kwargs, fn.Env)
+		if err != nil {
+			return err
+		}
+
+		inst := &object.Instance{Struct: fn, Fields: make(map[string]object.Object)}
+		for _, field := range fn.Fields.Identifiers {
+			inst.Fields[field.Value], _ = fieldEnv.Get(field.Value)
+		}
+
+		return inst
 
 	case *object.Builtin:
-		return fn.(*object.Builtin).Fn(args.Value...)
+		if len(kwargs) > 0 {
+			return errorOfKind(object.ARGUMENT_ERROR, "%s does not take keyword arguments", name)
+		}
+		return fn.Fn(args.Value...)
 	default:
-		return errorMessageToObject("Function not found: %s", name)
+		return errorOfKind(object.TYPE_ERROR, "not a function: %s", fn.Type())
 	}
 }
 
+// evaluates a node and stamps errors that do not have a position yet with the position of node
 func evalProgram(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() && node != nil {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		extendedEnv := object.ExtendEnv(env)
 		return evalStatements(node.Statements, extendedEnv, false)
 	case *ast.FuncStatement:
 		return evalFuncStatement(node, env)
-	case *ast.CallExpression:
-		args := evalExpressionList(node.ArgumentList, env)
-		if isError(args) {
-			return args
+	case *ast.StructStatement:
+		return evalStructStatement(node, env)
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *ast.DeferStatement:
+		return evalDeferStatement(node, env)
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+	case *ast.ThrowStatement:
+		value := evalProgram(node.Value, env)
+		if isError(value) {
+			return value
 		}
-		return evalCallExpression(node.FunctionName.Value, args, env)
+		return thrownError(value)
+	case *ast.FunctionLiteral:
+		return &object.Function{ParameterList: node.ParameterList, FuncBody: node.FuncBody, Env: env}
+	case *ast.CallExpression:
+		return evalCallExpression(node, env)
 	case *ast.ForStatement:
 		return evalForStatement(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.SwitchStatement:
+		return evalSwitchStatement(node, env)
 	case *ast.IfStatement:
 		return evalIfStatement(node, env)
 	case *ast.BlockStatement:
@@ -470,12 +1350,46 @@ func evalProgram(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.FieldExpression:
+		left := evalProgram(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		return evalFieldExpression(left, node.Field.Value)
+	case *ast.SliceExpression:
+		left := evalProgram(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		var start, end object.Object
+		if node.Start != nil {
+			if start = evalProgram(node.Start, env); isError(start) {
+				return start
+			}
+		}
+		if node.End != nil {
+			if end = evalProgram(node.End, env); isError(end) {
+				return end
+			}
+		}
+		return evalSliceExpression(left, start, end)
+	case *ast.MapLiteral:
+		return evalMapLiteral(node, env)
 	case *ast.PrefixExpression:
 		right := evalProgram(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
+	case *ast.ConditionalExpression:
+		cond := evalProgram(node.Condition, env)
+		if isError(cond) {
+			return cond
+		}
+		if isTrue(cond) {
+			return evalProgram(node.Consequence, env)
+		}
+		return evalProgram(node.Alternative, env)
 	case *ast.InfixExpression:
 		right := evalProgram(node.Right, env)
 		if isError(right) {
@@ -490,8 +1404,12 @@ func evalProgram(node ast.Node, env *object.Environment) object.Object {
 		return evalIdentifier(node, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.String:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	}
@@ -500,6 +1418,8 @@ func evalProgram(node ast.Node, env *object.Environment) object.Object {
 }
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	// every evaluation loads the files it imports afresh
+	env.SetImports(object.NewImports())
 	env = environmentwithBuiltins(env)
 	out := evalStatements(node.(*ast.Program).Statements, env, false)
 	switch out.(type) {
@@ -1,11 +1,15 @@
 package eval
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/pandeykartikey/goto/lexer"
 	"github.com/pandeykartikey/goto/object"
 	"github.com/pandeykartikey/goto/parser"
+	"github.com/pandeykartikey/goto/token"
 )
 
 func evalInput(inp string) object.Object {
@@ -63,6 +67,74 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func testFloatObject(t *testing.T, obj object.Object, exp float64) bool {
+	floatobj, ok := obj.(*object.Float)
+
+	if !ok {
+		t.Errorf("Expected object type to be float. got=%T", obj)
+		return false
+	}
+
+	if floatobj.Value != exp {
+		t.Errorf("Expected %v but got %v instead", exp, floatobj.Value)
+		return false
+	}
+
+	return true
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"5.5 % 2", 1.5},
+		{"2 ** -1", 0.5},
+		{"2.0 ** 3", 8},
+		{"1e3 / 4", 250},
+		{"float(3)", 3},
+		{`float("2.25")`, 2.25},
+		{"var a = 2.5; -a; a", 2.5},
+	}
+
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		testFloatObject(t, out, tt.exp)
+	}
+}
+
+func TestNumericConversions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   int64
+	}{
+		{"2 ** 10", 1024},
+		{"3 ** 39", 4052555153018976267},
+		{"2 ** 62", 4611686018427387904},
+		{"(-2) ** 63", -9223372036854775808},
+		{"1 ** 100", 1},
+		{"(-1) ** 101", -1},
+		{"int(3.99)", 3},
+		{"int(-3.99)", -3},
+		{`int("42")`, 42},
+		{`int("010")`, 10},
+		{`int("-7")`, -7},
+		{"int(true)", 1},
+		{"var a = 5; -a; a", 5},
+	}
+
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		testIntegerObject(t, out, tt.exp)
+	}
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, exp bool) bool {
 	boolobj, ok := obj.(*object.Boolean)
 
@@ -104,6 +176,11 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"1 || 0", true},
 		{"true || 0", true},
 		{"true && true", true},
+		{"1.5 < 2", true},
+		{"2 == 2.0", true},
+		{"0.1 + 0.2 > 0.3", true},
+		{"!0.0", true},
+		{"!!0.5", true},
 		{`"Hello" != "World"`, true},
 		{`"Hello" == "World"`, false},
 	}
@@ -138,6 +215,30 @@ func testStringObject(t *testing.T, obj object.Object, exp string) bool {
 	return true
 }
 
+func TestConditionalExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"true ? 1 : 2", "1"},
+		{"false ? 1 : 2", "2"},
+		{"var x = 5; x > 3 ? \"big\" : \"small\"", "big"},
+		{"var n = 0; var label = n == 0 ? \"zero\" : n < 0 ? \"negative\" : \"positive\"; label;", "zero"},
+		{"var n = -4; n == 0 ? \"zero\" : n < 0 ? \"negative\" : \"positive\"", "negative"},
+		{"func abs(x) { return x < 0 ? -x : x; }; abs(-3) + abs(2);", "5"},
+		// only the branch that is taken is evaluated
+		{"true ? 1 : missing", "1"},
+		{"false ? 1 / 0 : 2", "2"},
+		{"var calls = 0; func hit() { calls++; return calls; }; true ? 0 : hit(); calls;", "0"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
 func TestEvalStringExpression(t *testing.T) {
 	tests := []struct {
 		input string
@@ -145,6 +246,12 @@ func TestEvalStringExpression(t *testing.T) {
 	}{
 		{`"Hello" + " " + "World"`, "Hello World"},
 		{`var a = "Hello"; a + " World"`, "Hello World"},
+		{`"tab\tend"`, "tab\tend"},
+		{"`multi\nline`", "multi\nline"},
+		{`var name = "goto"; "hello ${name}!"`, "hello goto!"},
+		{`var items = [1, 2]; "${len(items)} items, first ${items[0] * 10}"`, "2 items, first 10"},
+		{`"${1.5} ${true} ${[1, "a"]}"`, "1.5 true [1, a]"},
+		{`var a = "x"; "${"<${a}>"}"`, "<x>"},
 	}
 
 	for _, tt := range tests {
@@ -260,6 +367,42 @@ func TestErrorHandling(t *testing.T) {
 			"[1, 2, 3][-1]",
 			"List index out of range",
 		},
+		{
+			"1 / 0",
+			"Division by zero",
+		},
+		{
+			"3 ** 40",
+			"integer overflow in **",
+		},
+		{
+			"2 ** 64",
+			"integer overflow in **",
+		},
+		{
+			"1.5 + true",
+			"Type Mismatch: FLOAT + BOOLEAN",
+		},
+		{
+			`int("abc")`,
+			`could not convert "abc" to INTEGER`,
+		},
+		{
+			`int("0x1f")`,
+			`could not convert "0x1f" to INTEGER`,
+		},
+		{
+			`int("1_000")`,
+			`could not convert "1_000" to INTEGER`,
+		},
+		{
+			`int(1e300)`,
+			"could not convert 1e+300 to INTEGER",
+		},
+		{
+			`"value: ${missing}"`,
+			"Identifier not found: missing",
+		},
 		{
 			`len(1)`,
 			"argument to `len` not supported, got INTEGER",
@@ -268,6 +411,126 @@ func TestErrorHandling(t *testing.T) {
 			`len("one", "two")`,
 			"wrong number of arguments. got=2, want=1",
 		},
+		{
+			"var x = 5; x(1);",
+			"not a function: INTEGER",
+		},
+		{
+			"[1, 2][0]()",
+			"not a function: INTEGER",
+		},
+		{
+			"missing(1)",
+			"Function not found: missing",
+		},
+		{
+			`var m = {"a": 1}; m["b"];`,
+			"Key not found: b",
+		},
+		{
+			`var m = {[1]: 2};`,
+			"unusable as map key: LIST",
+		},
+		{
+			`var m = {}; m[[1]] = 2;`,
+			"unusable as map key: LIST",
+		},
+		{
+			`var l = [1]; l[1] = 2;`,
+			"List index out of range",
+		},
+		{
+			`var grid = [[0]]; grid[0][-1] = 2;`,
+			"List index out of range",
+		},
+		{
+			`var s = "abc"; s[0] = "x";`,
+			"index assignment not supported: STRING",
+		},
+		{
+			`switch 1 { case "a": 1; }`,
+			"Type Mismatch: INTEGER == STRING",
+		},
+		{
+			"for x in 5 { }",
+			"cannot iterate over INTEGER",
+		},
+		{
+			"for x in [1] { } x;",
+			"Identifier not found: x",
+		},
+		{
+			"var a = 1; a /= 0;",
+			"Division by zero",
+		},
+		{
+			"b += 1;",
+			"Identifier not found: b",
+		},
+		{
+			"var a = [1]; a[3]++;",
+			"List index out of range",
+		},
+		{
+			`var a = true; a += 1;`,
+			"Type Mismatch: BOOLEAN + INTEGER",
+		},
+		{
+			`[1, 2]["a":]`,
+			"slice bounds must be INTEGER, got STRING",
+		},
+		{
+			`var n = 5; n[1:2];`,
+			"slice operator not supported: INTEGER",
+		},
+		{
+			`keys([1])`,
+			"argument to `keys` must be MAP, got LIST",
+		},
+		{
+			"func f(a, b = 1) { }; f();",
+			"f missing argument a",
+		},
+		{
+			"func f(a) { }; f(1, 2);",
+			"Number of arguments passed donot match f's number of parameters",
+		},
+		{
+			"func f(a) { }; f(b: 1);",
+			"f got an unexpected keyword argument b",
+		},
+		{
+			"func f(a) { }; f(1, a: 2);",
+			"f got multiple values for argument a",
+		},
+		{
+			"func f(...rest) { }; f(rest: 1);",
+			"f got an unexpected keyword argument rest",
+		},
+		{
+			`len(x: "a")`,
+			"len does not take keyword arguments",
+		},
+		{
+			"struct P { x; } var p = P(1); p.y;",
+			"P has no field or method y",
+		},
+		{
+			"struct P { x; } var p = P(1); p.y = 2;",
+			"P has no field y",
+		},
+		{
+			"struct P { x, y; } P(1);",
+			"P missing argument y",
+		},
+		{
+			"var n = 1; n.x;",
+			"field access not supported: INTEGER",
+		},
+		{
+			"var n = [1]; n.x = 1;",
+			"field assignment not supported: LIST",
+		},
 	}
 
 	for _, tt := range tests {
@@ -357,6 +620,323 @@ func TestFunctionCall(t *testing.T) {
 	}
 }
 
+func TestFirstClassFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   int64
+	}{
+		{"var double = func(x) { return x * 2; }; double(4);", 8},
+		{"func(x) { return x + 1; }(2);", 3},
+		{"func apply(f, x) { return f(x); }; apply(func(x) { return x * x; }, 5);", 25},
+		{"func getDouble() { return func(x) { return x * 2; }; }; getDouble()(6);", 12},
+		{"var handlers = [func(x) { return x; }, func(x) { return -x; }]; handlers[1](3);", -3},
+		{"func one() { return 1; }; var f = one; f();", 1},
+		{"var size = len; size([1, 2, 3]);", 3},
+		{"var fns = [len]; fns[0](\"ab\");", 2},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		testIntegerObject(t, out, tt.exp)
+	}
+}
+
+func TestFunctionsWithoutResult(t *testing.T) {
+	noop := "var noop = func() {}; "
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{noop + "noop();", "null"},
+		{"func f() { var x = 1; }; f();", "null"},
+		{noop + "print(noop());", "null"},
+		{noop + `"got ${noop()}";`, "got null"},
+		{noop + "try { throw noop(); } catch e { e.message; }", "null"},
+		{noop + "for x in noop() { }", "Error: cannot iterate over NULL"},
+		{noop + "switch noop() { case 1: 1; }", "Error: Type Mismatch: NULL == INTEGER"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if err, ok := out.(*object.Error); ok {
+			err.Pos = token.Position{}
+		}
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   int64
+	}{
+		{"func makeAdder(x) { return func(y) { return x + y; }; }; makeAdder(1)(2);", 3},
+		{
+			`func makeCounter() {
+				var count = 0;
+				return func() { count = count + 1; return count; };
+			}
+			var counter = makeCounter();
+			counter();
+			counter();
+			counter();`,
+			3,
+		},
+		{
+			`func makeCounter() {
+				var count = 0;
+				return func() { count = count + 1; return count; };
+			}
+			var a = makeCounter();
+			var b = makeCounter();
+			a();
+			a();
+			b();`,
+			1,
+		},
+		{"func fact(n) { if n < 2 { return 1; } return n * fact(n - 1); }; fact(5);", 120},
+		{"func getY() { return y; }; var y = 3; getY();", 3},
+		{"var x = 1; func f(x) { return x; }; f(2);", 2},
+		{"var x = 1; func f(x) { x = 5; return x; }; f(2); x;", 1},
+		{"var total = 0; func add(n) { total = total + n; }; add(2); add(3); total;", 5},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		testIntegerObject(t, out, tt.exp)
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"func greet(name, greeting = \"Hi\") { return greeting + \" \" + name; }; greet(\"Bo\");", "Hi Bo"},
+		{"func greet(name, greeting = \"Hi\") { return greeting + \" \" + name; }; greet(\"Bo\", \"Yo\");", "Yo Bo"},
+		{"func f(a, b = a * 2) { return b; }; f(3);", "6"},
+		{"func f(a, b = 1, c = 2) { return [a, b, c]; }; f(0, c: 5);", "[0, 1, 5]"},
+		{"func f(a, b) { return a - b; }; f(b: 1, a: 5);", "4"},
+		{"func sum(...nums) { var n = 0; for x in nums { n += x; } return n; }; sum(1, 2, 3);", "6"},
+		{"func f(a, ...rest) { return rest; }; f(1, 2, 3);", "[2, 3]"},
+		{"func f(a, ...rest) { return len(rest); }; f(1);", "0"},
+		{"var f = func(a, b = 10) { return a + b; }; f(b: 1, a: 1);", "2"},
+		// defaults are evaluated on every call
+		{"var n = 0; func next(step = n + 1) { n = step; return n; }; next(); next(); next();", "3"},
+		// and see the scope the function was defined in
+		{"var base = 1; func f(x = base) { return x; }; func g() { var base = 2; return f(); }; g();", "1"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	point := "struct Point { x, y = 0; func norm() { return self.x * self.x + self.y * self.y; } " +
+		"func move(dx, dy = 0) { self.x += dx; self.y += dy; return self; } }; "
+
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{point + "Point(1, 2);", "Point{x: 1, y: 2}"},
+		{point + "Point(1);", "Point{x: 1, y: 0}"},
+		{point + "Point(y: 5, x: 2);", "Point{x: 2, y: 5}"},
+		{point + "var p = Point(3, 4); p.x + p.y;", "7"},
+		{point + "Point(3, 4).norm();", "25"},
+		{point + "var p = Point(1, 1); p.x = 5; p.y += 2; p.x++; p;", "Point{x: 6, y: 3}"},
+		{point + "var p = Point(0); p.move(1).move(1, dy: 2); p;", "Point{x: 2, y: 2}"},
+		{point + "var p = Point(3, 4); var n = p.norm; p.x = 0; n();", "16"},
+		{point + "var ps = [Point(1), Point(2)]; ps[1].x = 7; ps;", "[Point{x: 1, y: 0}, Point{x: 7, y: 0}]"},
+		{point + "var a = Point(1); var b = a; b.x = 9; a.x;", "9"},
+		{point + "Point;", "struct Point { x,y = 0 }"},
+		{"struct Empty { } Empty();", "Empty{}"},
+		{"struct Box { v; } Box(Box(1));", "Box{v: Box{v: 1}}"},
+		{"struct Counter { n = 0; func inc() { self.n++; } } var c = Counter(); c.inc(); c.inc(); c.n;", "2"},
+		// methods see the scope the struct was declared in
+		{"var step = 10; struct S { v; func next() { return self.v + step; } } S(1).next();", "11"},
+		{"## A point\nstruct P { x; } help(P);", "A point"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`var out = ""; try { [1][3]; out = "no"; } catch e { out = e.kind + ": " + e.message; } out;`, "IndexError: List index out of range"},
+		{`var out = ""; try { 1 + true; } catch e { out = e.kind; } out;`, "TypeError"},
+		{`var out = ""; try { missing; } catch e { out = e.kind; } out;`, "NameError"},
+		{`var out = ""; var m = {"a": 1}; try { m["b"]; } catch e { out = e.kind; } out;`, "KeyError"},
+		{`var out = ""; try { 1 / 0; } catch e { out = e.kind; } out;`, "ArithmeticError"},
+		{`var out = ""; try { len(1, 2); } catch e { out = e.kind; } out;`, "ArgumentError"},
+		{`var out = ""; try { throw "boom"; } catch e { out = e.kind + ": " + e.message; } out;`, "Error: boom"},
+		{`var out = ""; try { throw 42; } catch e { out = e.message; } out;`, "42"},
+		{`var out = ""; try { throw Error("bad", kind: "ValueError"); } catch e { out = e.kind; } out;`, "ValueError"},
+		{`try { throw "boom"; } catch e { e; }`, "Error{message: boom, kind: Error}"},
+		{`var n = 0; try { n = 1; } catch { n = 2; } n;`, "1"},
+		{`var n = 0; try { throw "x"; } catch { n = 2; } n;`, "2"},
+		// errors from called functions are caught too
+		{`func f(l) { return l[5]; }; var out = ""; try { f([1]); } catch e { out = e.message; } out;`, "List index out of range"},
+		// a rethrown error keeps its kind
+		{`var out = ""; try { try { x; } catch e { throw e; } } catch e { out = e.kind; } out;`, "NameError"},
+		// finally runs on every way out of the try
+		{`var log = ""; try { log += "a"; } finally { log += "f"; } log;`, "af"},
+		{`var log = ""; try { throw "x"; } catch { log += "c"; } finally { log += "f"; } log;`, "cf"},
+		{`var log = ""; func f() { try { return 1; } finally { log += "f"; } }; f(); log;`, "f"},
+		{`func f() { try { return 1; } finally { } }; f();`, "1"},
+		{`func f() { try { return 1; } finally { return 2; } }; f();`, "2"},
+		{`var log = ""; for x in [1, 2, 3] { try { if x == 2 { break; } } finally { log += "${x}"; } } log;`, "12"},
+		{`var log = ""; func f() { try { throw "x"; } finally { log += "f"; } }; try { f(); } catch { log += "c"; } log;`, "fc"},
+		{`try { throw "x"; } catch e { } e;`, "Error: Identifier not found: e"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if err, ok := out.(*object.Error); ok {
+			err.Pos = token.Position{}
+		}
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestDeferStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`var log = ""; func note(s) { log += s; }; func f() { defer note("a"); defer note("b"); note("c"); }; f(); log;`, "cba"},
+		// arguments are evaluated where defer is written
+		{`var log = ""; func note(s) { log += s; }; func f() { var x = "1"; defer note(x); x = "2"; }; f(); log;`, "1"},
+		// deferred calls run however the function returns
+		{`var log = ""; func note(s) { log += s; }; func f() { defer note("d"); if true { return 1; } }; f(); log;`, "d"},
+		{`var log = ""; func note(s) { log += s; }; func f() { defer note("d"); [1][2]; }; try { f(); } catch { } log;`, "d"},
+		{`var log = ""; func note(s) { log += s; }; func f() { defer note("d"); throw "x"; }; try { f(); } catch { log += "c"; } log;`, "dc"},
+		{`var log = ""; func note(s) { log += s; }; func f() { for x in ["1", "2"] { defer note(x); } }; f(); log;`, "21"},
+		{`func f() { defer len("a"); return 5; }; f();`, "5"},
+		// each call runs its own deferred calls
+		{`var log = ""; func note(s) { log += s; }; func g() { defer note("g"); }; func f() { defer note("f"); g(); note("-"); }; f(); log;`, "g-f"},
+		{`var log = ""; func f() { defer func() { log += "closure"; }(); }; f(); log;`, "closure"},
+		{`var log = ""; func f() { defer func() { log += "f"; }(); return func() { defer func() { log += "g"; }(); log += "-"; }; }; var g = f(); g(); log;`, "f-g"},
+		{`struct S { n = 0; func inc() { self.n++; } } func f(s) { defer s.inc(); defer s.inc(); }; var s = S(); f(s); s.n;`, "2"},
+		// an error in a deferred call replaces the result
+		{`func f() { defer len(1); return 5; }; f();`, "Error: argument to `len` not supported, got INTEGER"},
+		{`func f() { defer missing(); }; f();`, "Error: Function not found: missing"},
+		{`defer print(1);`, "Error: defer used outside function"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if err, ok := out.(*object.Error); ok {
+			err.Pos = token.Position{}
+		}
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"const X = 5; X * 2;", "10"},
+		{"const A, B = 1, 2; A + B;", "3"},
+		{"const L = [1]; L[0] = 5; L;", "[5]"},
+		{"const X = 1; func f() { X = 2; }; f();", "Error: cannot assign to constant X"},
+		{"const X = 1; func f() { X += 2; }; f();", "Error: cannot assign to constant X"},
+		{"const X = 1; func f() { var X = 2; }; f();", "Error: cannot redeclare constant X"},
+		{"const X = 1; func f() { func X() { } }; f();", "Error: cannot redeclare constant X"},
+		{"func f() { const Y = 1; Y = 2; }; f();", "Error: cannot assign to constant Y"},
+		{"func f() { const Y = 1; return Y; }; f() + f();", "2"},
+		// nor can parameters, loop variables or catch names
+		{"const X = 1; func f(X) { return X; }; f(2);", "Error: cannot redeclare constant X"},
+		{"const X = 1; func f(a, ...X) { return a; }; f(2);", "Error: cannot redeclare constant X"},
+		{"func f(len) { return len; }; f(2);", "Error: cannot redeclare constant len"},
+		{"var f = func(print) { }; f(1);", "Error: cannot redeclare constant print"},
+		{"const X = 1; for X in [1, 2] { }", "Error: cannot redeclare constant X"},
+		{"for i, print in [1, 2] { }", "Error: cannot redeclare constant print"},
+		{"try { throw 1; } catch print { }", "Error: cannot redeclare constant print"},
+		{"var n = 0; for x in [1, 2] { n += x; } try { throw 1; } catch e { n += 1; } n;", "4"},
+		// builtins are constants
+		{"func f() { var len = 1; }; f();", "Error: cannot redeclare constant len"},
+		{"func f() { print = 1; }; f();", "Error: cannot assign to constant print"},
+		{"func f() { struct Error { } }; f();", "Error: cannot redeclare constant Error"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if err, ok := out.(*object.Error); ok {
+			err.Pos = token.Position{}
+		}
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		kind    string
+	}{
+		{`throw "boom";`, "boom", object.GENERIC_ERROR},
+		{`throw Error("bad", kind: "ValueError");`, "bad", "ValueError"},
+		{`try { throw "boom"; } finally { }`, "boom", object.GENERIC_ERROR},
+		{`try { } finally { [1][2]; }`, "List index out of range", object.INDEX_ERROR},
+		{`try { throw "a"; } catch { throw "b"; }`, "b", object.GENERIC_ERROR},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		err, ok := out.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T", tt.input, out)
+			continue
+		}
+		if err.Message != tt.message || err.Kind != tt.kind {
+			t.Errorf("wrong error for %q. expected=%s %q, got=%s %q", tt.input, tt.kind, tt.message, err.Kind, err.Message)
+		}
+	}
+}
+
+func TestLexicalScoping(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{
+			// the callee does not see the caller's variables
+			"func f() { return x; }; func g() { var x = 5; return f(); }; g();",
+			"Identifier not found: x",
+		},
+		{
+			// variables declared in a function body do not leak into the caller
+			"func f() { var z = 1; return z; }; f(); z;",
+			"Identifier not found: z",
+		},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		errObj, ok := out.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T", out)
+			continue
+		}
+		if errObj.Message != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errObj.Message)
+		}
+	}
+}
+
 func TestForStatement(t *testing.T) {
 	tests := []struct {
 		input string
@@ -386,6 +966,71 @@ func TestForStatement(t *testing.T) {
 	}
 }
 
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"var total = 0; for x in [1, 2, 3] { total += x; } total;", "6"},
+		{"var out = [5, 6]; for i, x in [5, 6] { out[i] = i * 10 + x; } out;", "[5, 16]"},
+		{`var out = ""; for ch in "héllo" { out = ch + out; } out;`, "olléh"},
+		{`var out = ""; for i, ch in "ab" { out += "${i}${ch}"; } out;`, "0a1b"},
+		{`var m = {"a": 1, "b": 2}; var out = ""; for k in m { out += k; } out;`, "ab"},
+		{`var m = {"a": 1, "b": 2}; var total = 0; for k, v in m { total += v; } total;`, "3"},
+		{`var m = {"a": 1, "b": 2, "c": 3}; var n = 0; for k in m { delete(m, "b"); n++; } n;`, "2"},
+		{"var n = 0; for x in [1, 2, 3, 4] { if x == 3 { break; } n += x; } n;", "3"},
+		{"var n = 0; for x in [1, 2, 3, 4] { if x % 2 == 0 { continue; } n += x; } n;", "4"},
+		{"var i = 0; while i < 5 { i++; } i;", "5"},
+		{"var i = 0; while true { i++; if i == 3 { break; } } i;", "3"},
+		{"var i, n = 0, 0; while i < 5 { i++; if i % 2 == 0 { continue; } n += i; } n;", "9"},
+		{"var i = 0; for ;; { i++; if i > 3 { break; } } i;", "4"},
+		{"func first(l) { for x in l { if x > 1 { return x; } } return 0; }; first([1, 5, 7]);", "5"},
+		{"var n = 0; for x in [[1, 2], [3]] { for y in x { n += y; } } n;", "6"},
+		// every iteration has its own scope
+		{"var n = 0; for x in [1, 2, 3] { var sq = x * x; n += sq; } n;", "14"},
+		{"var i = 0; while i < 3 { var seen = i; i++; } i;", "3"},
+		{"for var i = 0; i < 3; i++ { var x = i; } i;", "3"},
+		{"var fns = [0, 0]; for i, x in [1, 2] { fns[i] = func() { return x; }; } fns[0]() + fns[1]();", "3"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestSwitchStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`var out = ""; switch "b" { case "a": out = "A"; case "b", "c": out = "BC"; default: out = "?"; } out;`, "BC"},
+		{`var out = ""; switch "z" { case "a": out = "A"; default: out = "?"; } out;`, "?"},
+		{`var out = ""; switch "z" { default: out = "?"; case "z": out = "Z"; } out;`, "Z"},
+		{`var out = 0; switch 5 { case 1: out = 1; } out;`, "0"},
+		{`var out = ""; switch 2 { case 2.0: out = "float"; } out;`, "float"},
+		{`var n = 7; var out = ""; switch { case n < 5: out = "small"; case n < 10: out = "medium"; } out;`, "medium"},
+		{`var out = 0; var ok = false; switch 1 { case 1 if ok: out = 1; case 1: out = 2; } out;`, "2"},
+		{`var out = 0; switch 1 { case 1: out += 1; fallthrough; case 2: out += 10; case 3: out += 100; } out;`, "11"},
+		{`var out = 0; switch 1 { case 1: out = 1; break; out = 2; } out;`, "1"},
+		{`var out = 0; switch 1 { case 1: if true { break; } out = 2; } out;`, "0"},
+		// break ends the switch, not the enclosing loop
+		{`var n = 0; for x in [1, 2, 3] { switch x { case 2: break; } n += x; } n;`, "6"},
+		// continue is passed on to the enclosing loop
+		{`var n = 0; for x in [1, 2, 3] { switch x { case 2: continue; } n += x; } n;`, "4"},
+		{`var n = 0; for x in [1, 2, 3, 4] { switch { case x == 3: break; } if x == 3 { break; } n += x; } n;`, "3"},
+		{`func name(n) { switch n { case 1: return "one"; default: return "many"; } }; name(1) + name(2);`, "onemany"},
+		{`var x = 1; switch 1 { case 1: var x = 2; } x;`, "1"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
 func TestList(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 	out := evalInput(input)
@@ -420,6 +1065,14 @@ func TestIndexExpressions(t *testing.T) {
 			`"abc"[1]`,
 			"b",
 		},
+		{
+			`"héllo"[1]`,
+			"é",
+		},
+		{
+			`"日本語"[2]`,
+			"語",
+		},
 		{
 			"[1, 2, 3][2]",
 			3,
@@ -456,6 +1109,166 @@ func TestIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestMaps(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   interface{}
+	}{
+		{`var m = {"name": "goto", 1: true}; m["name"];`, "goto"},
+		{`var m = {"name": "goto", 1: true}; m[1];`, true},
+		{`var m = {true: 1, false: 0}; m[1 > 2];`, int64(0)},
+		{`var k = "a"; var m = {k + "b": 2}; m["ab"];`, int64(2)},
+		{`var m = {"a": 1}; m["a"] = 5; m["a"];`, int64(5)},
+		{`var m = {}; m["x"] = 1; m["y"] = 2; len(m);`, int64(2)},
+		{`var m = {"a": 1, "a": 2}; len(m);`, int64(1)},
+		{`var m = {"a": 1}; has(m, "a");`, true},
+		{`var m = {"a": 1}; has(m, "b");`, false},
+		{`var m = {"a": 1, "b": 2}; delete(m, "a"); has(m, "a");`, false},
+		{`var m = {"a": {"b": 3}}; m["a"]["b"];`, int64(3)},
+		{`var m = {1: "x"}; var n = m; n[1] = "y"; m[1];`, "y"},
+		{`var l = [1, 2]; l[0] = 7; l[0];`, int64(7)},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		switch exp := tt.exp.(type) {
+		case int64:
+			testIntegerObject(t, out, exp)
+		case string:
+			testStringObject(t, out, exp)
+		case bool:
+			testBooleanObject(t, out, exp)
+		}
+	}
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"var a = [1, 2, 3]; a[1] = 5; a;", "[1, 5, 3]"},
+		{"var a = [1, 2]; a[0], a[1] = a[1], a[0]; a;", "[2, 1]"},
+		{"var i = 0; var a = [0, 0]; i, a[i] = 1, 5; a;", "[5, 0]"},
+		{"var a = [[1], [2]]; var b = a; a, b[0][0] = 7, 3; b;", "[[3], [2]]"},
+		{"var grid = [[0, 0], [0, 0]]; var x, y = 1, 0; grid[y][x] = 1; grid;", "[[0, 1], [0, 0]]"},
+		{`var m = {"xs": [1, 2]}; m["xs"][0] = 9; m;`, "{xs: [9, 2]}"},
+		{`var l = [{"a": 1}]; l[0]["b"] = 2; l;`, "[{a: 1, b: 2}]"},
+		{"var a, b = [1], 2; a[0], b = b, a[0]; [a, b];", "[[2], 1]"},
+		{"var a = [1, 2]; func set(l) { l[0] = 7; }; set(a); a;", "[7, 2]"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][1:10]", "[2, 3, 4]"},
+		{"[1, 2, 3, 4][-10:1]", "[1]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{`"hello"[1:3]`, "el"},
+		{`"héllo"[:2]`, "hé"},
+		{`"hello"[-3:]`, "llo"},
+		{`"日本語"[1:]`, "本語"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+// slices are copies, changing one leaves the list it was taken from alone
+func TestSlicesDoNotShareStorage(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"var a = [1, 2, 3]; var b = a[0:2]; b[0] = 9; a;", "[1, 2, 3]"},
+		{"var a = [1, 2, 3]; var b = a[0:2]; append(b, 7); a;", "[1, 2, 3]"},
+		{"var a = [1, 2, 3]; var b = a[:]; a[2] = 0; b;", "[1, 2, 3]"},
+		// the elements themselves are not copied
+		{"var a = [[1], [2]]; var b = a[:1]; b[0][0] = 5; a;", "[[5], [2]]"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"var a = 5; a += 2; a;", "7"},
+		{"var a = 5; a -= 2; a;", "3"},
+		{"var a = 5; a *= 2; a;", "10"},
+		{"var a = 5; a /= 2; a;", "2"},
+		{"var a = 5; a %= 2; a;", "1"},
+		{"var a = 2; a **= 3; a;", "8"},
+		{"var a = 1; a += 0.5; a;", "1.5"},
+		{`var s = "go"; s += "to"; s;`, "goto"},
+		{"var i = 0; i++; i++; i;", "2"},
+		{"var i = 0; i--; i;", "-1"},
+		{"var a = [1, 2]; a[1] += 5; a;", "[1, 7]"},
+		{`var m = {"n": 1}; m["n"]++; m;`, "{n: 2}"},
+		{"var grid = [[1]]; grid[0][0] *= 4; grid;", "[[4]]"},
+		{"var a, b = 1, 2; a, b += 10, 20; [a, b];", "[11, 22]"},
+		{"var total = 0; for var i = 0; i < 5; i++ { total += i; } total;", "10"},
+		{"var n = 0; for var i = 0; i < 10; i += 3 { n++; } n;", "4"},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
+func TestMapIteration(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`var m = {"b": 1, "a": 2, 3: 3}; m;`, "{b: 1, a: 2, 3: 3}"},
+		{`var m = {"b": 1, "a": 2}; m["c"] = 3; keys(m);`, "[b, a, c]"},
+		{`var m = {"b": 1, "a": 2}; m["b"] = 5; values(m);`, "[5, 2]"},
+		{`var m = {"a": 1, "b": 2, "c": 3}; delete(m, "b"); keys(m);`, "[a, c]"},
+		{
+			`var m = {"a": 1, "b": 2, "c": 3};
+			var ks = keys(m);
+			var total = 0;
+			for var i = 0; i < len(ks); i = i + 1 {
+				total = total + m[ks[i]];
+			}
+			total;`,
+			"6",
+		},
+	}
+	for _, tt := range tests {
+		out := evalInput(tt.input)
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input string
@@ -465,6 +1278,11 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
 		{`len([1,2,3])`, 3},
+		{`len("héllo")`, 5},
+		{`len("日本語")`, 3},
+		{`len(bytes("héllo"))`, 6},
+		{`bytes("é")[0]`, 195},
+		{`var ü = 2; ü * 2`, 4},
 		{`
 			var a = [1,2,3];
 			append(a, 4);
@@ -478,3 +1296,108 @@ func TestBuiltinFunctions(t *testing.T) {
 		testIntegerObject(t, out, tt.exp)
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"var a = 1;\nprint(a + foo);", "Error: test.to:2:11: Identifier not found: foo"},
+		{"var a = 1;\n  a + true;", "Error: test.to:2:5: Type Mismatch: INTEGER + BOOLEAN"},
+		{"func f(x) {\n\treturn x[3];\n}\nf([1]);", "Error: test.to:2:10: List index out of range"},
+		{"var s = \"x\";\nlen(s, s);", "Error: test.to:2:1: wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewFile("test.to", tt.input)
+		program := parser.New(l).ParseProgram()
+		out := Eval(program, object.NewEnvironment())
+
+		errObj, ok := out.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T", out)
+			continue
+		}
+		if errObj.Inspect() != tt.exp {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.exp, errObj.Inspect())
+		}
+	}
+}
+
+func TestHelpBuiltin(t *testing.T) {
+	input := `## Doubles x.
+func double(x) { return x * 2; }
+help(double)`
+
+	testStringObject(t, evalInput(input), "Doubles x.")
+	testStringObject(t, evalInput("func f() {} help(f)"), "")
+}
+
+// writes files, relative paths mapped to their content, into a new temporary directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "goto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestImports(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib/util.to":   `import "./helper.to" as h; var items = [h.one()]; func double(x) { return x * 2; }`,
+		"lib/helper.to": `func one() { return 1; }`,
+		"lib/a.to":      `import "b.to"; var x = 1;`,
+		"lib/b.to":      `import "a.to"; var y = 1;`,
+		"std/strs.to":   `func shout(s) { return s + "!"; }`,
+		"helper.to":     `func one() { return 2; }`,
+	})
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv(searchPathVariable, os.Getenv(searchPathVariable))
+	os.Setenv(searchPathVariable, filepath.Join(dir, "std"))
+
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`import "lib/util.to" as util; util.double(4);`, "8"},
+		{`import "lib/util.to"; util.items;`, "[1]"},
+		{`import "helper.to"; helper.one();`, "2"},
+		{`import "strs.to"; strs.shout("hi");`, "hi!"},
+		// a module is evaluated once and shared by everything importing it
+		{`import "lib/util.to" as a; import "lib/util.to" as b; a.items[0] = 5; b.items[0];`, "5"},
+		// but every run loads it afresh
+		{`import "lib/util.to"; util.items;`, "[1]"},
+		{`import "lib/util.to"; util.h;`, "module helper (" + filepath.Join(dir, "lib", "helper.to") + ")"},
+		{`import "lib/util.to"; util.len;`, "Error: module util has no member len"},
+		{`import "lib/util.to"; util.nope;`, "Error: module util has no member nope"},
+		{`import "missing.to";`, `Error: cannot find module "missing.to"`},
+		{`import "./strs.to";`, `Error: cannot find module "./strs.to"`},
+		{`import "lib/a.to";`, "Error: import cycle: " + filepath.Join(dir, "lib", "a.to") + " -> " +
+			filepath.Join(dir, "lib", "b.to") + " -> " + filepath.Join(dir, "lib", "a.to")},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewFile(filepath.Join(dir, "main.to"), tt.input)
+		program := parser.New(l).ParseProgram()
+		out := Eval(program, object.NewEnvironment())
+
+		if err, ok := out.(*object.Error); ok {
+			err.Pos = token.Position{}
+		}
+		if out == nil || out.Inspect() != tt.exp {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.exp, out)
+		}
+	}
+}
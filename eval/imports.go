@@ -0,0 +1,143 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pandeykartikey/goto/ast"
+	"github.com/pandeykartikey/goto/lexer"
+	"github.com/pandeykartikey/goto/object"
+	"github.com/pandeykartikey/goto/parser"
+)
+
+// environment variable listing the directories searched for imports, separated like PATH
+const searchPathVariable = "GOTOPATH"
+
+func evalImportStatement(stmt *ast.ImportStatement, env *object.Environment) object.Object {
+	importer := stmt.Token.Pos.Filename
+
+	path, ok := resolveImport(stmt.Path.Value, importer)
+	if !ok {
+		return errorMessageToObject("cannot find module %q", stmt.Path.Value)
+	}
+
+	imports := env.Imports()
+	if imports == nil {
+		imports = object.NewImports()
+	}
+
+	// the script that started the imports is part of any cycle too
+	if len(imports.Loading) == 0 && importer != "" {
+		if abs, err := filepath.Abs(importer); err == nil {
+			imports.Loading = append(imports.Loading, object.LoadingFile{Abs: abs, Path: importer})
+			defer func() { imports.Loading = nil }()
+		}
+	}
+
+	module := loadModule(path, imports)
+	if isError(module) {
+		return module
+	}
+
+	name := module.(*object.Module).Name
+	if stmt.Name != nil {
+		name = stmt.Name.Value
+	}
+
+	if env.IsConstant(name) {
+		return errorMessageToObject("cannot redeclare constant %s", name)
+	}
+	if _, ok := env.Create(name, module); !ok {
+		return errorMessageToObject("An identifier already exists with that name")
+	}
+
+	return nil
+}
+
+// finds the file imported as path by the file importer. Relative paths are looked up next to the
+// importing file and then, unless they start with ./ or ../, in the directories of GOTOPATH.
+func resolveImport(path, importer string) (string, bool) {
+	var candidates []string
+
+	if filepath.IsAbs(path) {
+		candidates = append(candidates, path)
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(importer), path))
+
+		if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+			for _, dir := range filepath.SplitList(os.Getenv(searchPathVariable)) {
+				if dir != "" {
+					candidates = append(candidates, filepath.Join(dir, path))
+				}
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// parses and evaluates the file at path in a fresh environment, or returns it from the modules
+// already in imports. The module is named after the file.
+func loadModule(path string, imports *object.Imports) object.Object {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return errorMessageToObject("cannot import %s: %s", path, err)
+	}
+
+	if module, ok := imports.Modules[abs]; ok {
+		return module
+	}
+
+	for idx, file := range imports.Loading {
+		if file.Abs == abs {
+			var cycle []string
+			for _, file := range imports.Loading[idx:] {
+				cycle = append(cycle, file.Path)
+			}
+			return errorMessageToObject("import cycle: %s -> %s", strings.Join(cycle, " -> "), path)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return errorMessageToObject("cannot import %s: %s", path, err)
+	}
+	defer file.Close()
+
+	p := parser.New(lexer.NewFileReader(path, file))
+	program := p.ParseProgram()
+	if errors := p.ParseErrors(); len(errors) != 0 {
+		return errorMessageToObject("cannot import %s: %s", path, errors[0])
+	}
+
+	imports.Loading = append(imports.Loading, object.LoadingFile{Abs: abs, Path: path})
+	defer func() { imports.Loading = imports.Loading[:len(imports.Loading)-1] }()
+
+	// the top-level names of the module live in their own scope, apart from the builtins. Imports
+	// made by the module share the state of the import loading it.
+	root := environmentwithBuiltins(object.NewEnvironment())
+	root.SetImports(imports)
+	env := object.ExtendEnv(root)
+
+	switch out := evalStatements(program.Statements, env, false); out.(type) {
+	case *object.Error:
+		return out
+	case *object.ReturnValue:
+		return errorMessageToObject("return used outside function")
+	case *object.LoopControl:
+		return errorMessageToObject("break or continue used outside for loop")
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	module := &object.Module{Name: name, Path: path, Env: env}
+	imports.Modules[abs] = module
+
+	return module
+}
@@ -1,33 +1,82 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/pandeykartikey/goto/token"
 )
 
+// Lexer reads runes from its input one at a time, so a program never has to be held in memory
+// as a whole. It looks at most one rune ahead.
 type Lexer struct {
-	input        string
-	position     int
-	readPosition int
-	ch           byte
+	reader *bufio.Reader
+	err    error // first read error other than io.EOF
+	ch     rune
+	peek   rune
+
+	recording bool // whether readChar appends consumed runes to record
+	record    strings.Builder
+
+	filename string
+	line     int // line of l.ch
+	column   int // column of l.ch
+
+	// one entry per open ${ interpolation, counting the braces opened inside it
+	interpolations []int
 }
 
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.column++
 	}
 
-	l.position = l.readPosition
-	l.readPosition++
+	if l.recording && l.ch != 0 {
+		l.record.WriteRune(l.ch)
+	}
+
+	l.ch = l.peek
+	l.peek = l.readRune()
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+// returns 0 once the input is exhausted
+func (l *Lexer) readRune() rune {
+	if l.err != nil {
+		return 0
+	}
+
+	ch, _, err := l.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
 		return 0
 	}
 
-	return l.input[l.readPosition]
+	return ch
+}
+
+func (l *Lexer) peekChar() rune {
+	return l.peek
+}
+
+// starts recording the runes consumed by readChar, beginning with l.ch
+func (l *Lexer) mark() {
+	l.record.Reset()
+	l.recording = true
+}
+
+// stops recording and returns the runes consumed since mark
+func (l *Lexer) consumed() string {
+	l.recording = false
+	return l.record.String()
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -36,62 +85,321 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func (l *Lexer) skipComments() {
+// doc comments (##) are not skipped, they are read as DOC tokens
+func (l *Lexer) atComment() bool {
+	return l.ch == '#' && l.peekChar() != '#' || l.ch == '/' && l.peekChar() == '*'
+}
+
+// skips a # line comment or a /* */ block comment, returns false if a block comment is not terminated
+func (l *Lexer) skipComments() bool {
 	if l.ch == '#' {
 		for l.ch != '\n' && l.ch != 0 {
 			l.readChar()
 		}
+		return true
+	}
+
+	l.readChar()
+	l.readChar()
+	for !(l.ch == '*' && l.peekChar() == '/') {
+		if l.ch == 0 {
+			return false
+		}
+		l.readChar()
+	}
+	l.readChar()
+	l.readChar()
+
+	return true
+}
+
+// reads a ## doc comment up to the end of the line, dropping the ## marker and one following space
+func (l *Lexer) readDocComment() token.Token {
+	l.readChar()
+	l.readChar()
+	if l.ch == ' ' {
+		l.readChar()
+	}
+
+	l.mark()
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
 	}
+
+	return token.Token{Type: token.DOC, Literal: strings.TrimRight(l.consumed(), "\r")}
 }
 
-func (l *Lexer) readSequence(f func(byte) bool) string {
-	position := l.position
+func (l *Lexer) readSequence(f func(rune) bool) string {
+	var out strings.Builder
 
-	for f(l.ch) {
+	for l.ch != 0 && f(l.ch) {
+		out.WriteRune(l.ch)
 		l.readChar()
 	}
 
-	return l.input[position:l.position]
+	return out.String()
 }
 
+// reads an integer or a floating point literal such as 42, 0xFF, 1_000, 3.14 or 1e-9.
+// Digits are read leniently, the parser reports malformed literals.
+func (l *Lexer) readNumber() token.Token {
+	tok := token.Token{Type: token.INT}
+	l.mark()
+
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
+		l.readChar()
+		l.readChar()
+		l.readSequence(isAlphanumeric)
+		tok.Literal = l.consumed()
+		return tok
+	}
+
+	l.readSequence(isDigitOrUnderscore)
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tok.Type = token.FLOAT
+		l.readChar()
+		l.readSequence(isDigitOrUnderscore)
+	}
+
+	if (l.ch == 'e' || l.ch == 'E') && (isDigit(l.peekChar()) || l.peekChar() == '+' || l.peekChar() == '-') {
+		tok.Type = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readSequence(isDigit)
+	}
+
+	tok.Literal = l.consumed()
+	return tok
+}
+
+// reads a double quoted string and decodes its escape sequences. Malformed escapes do not stop
+// the scan so that the lexer resumes after the closing quote.
+//
+// A string containing ${ is split into template tokens: the text up to the first ${ is a
+// TEMPLATE_HEAD, the text between two interpolations a TEMPLATE_MIDDLE and the text after the
+// last one a TEMPLATE_TAIL. The tokens of the interpolated expressions are read in between.
+// continued is true when reading resumes after the } closing an interpolation.
+func (l *Lexer) readString(continued bool) token.Token {
+	var (
+		out strings.Builder
+		err string
+	)
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case '"':
+			l.readChar()
+			if err != "" {
+				return illegalToken("%s", err)
+			}
+			if continued {
+				return token.Token{Type: token.TEMPLATE_TAIL, Literal: out.String()}
+			}
+			return token.Token{Type: token.STRING, Literal: out.String()}
+		case 0, '\n':
+			return illegalToken("unterminated string literal")
+		case '\\':
+			l.readChar()
+			if l.ch == 0 || l.ch == '\n' {
+				return illegalToken("unterminated string literal")
+			}
+			if msg := l.readEscape(&out); msg != "" && err == "" {
+				err = msg
+			}
+		case '$':
+			if l.peekChar() != '{' {
+				out.WriteRune(l.ch)
+				continue
+			}
+			l.readChar()
+			l.readChar()
+			l.interpolations = append(l.interpolations, 0)
+			if err != "" {
+				return illegalToken("%s", err)
+			}
+			if continued {
+				return token.Token{Type: token.TEMPLATE_MIDDLE, Literal: out.String()}
+			}
+			return token.Token{Type: token.TEMPLATE_HEAD, Literal: out.String()}
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// decodes the escape sequence starting at l.ch into out, returns an error message if it is malformed
+func (l *Lexer) readEscape(out *strings.Builder) string {
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case 'a':
+		out.WriteByte('\a')
+	case 'b':
+		out.WriteByte('\b')
+	case 'f':
+		out.WriteByte('\f')
+	case 'v':
+		out.WriteByte('\v')
+	case '\\', '"', '\'', '$':
+		out.WriteRune(l.ch)
+	case 'x':
+		value, ok := l.readHexDigits(2)
+		if !ok {
+			return "invalid hex escape sequence, expected 2 hex digits after \\x"
+		}
+		out.WriteByte(byte(value))
+	case 'u', 'U':
+		esc, n := l.ch, 4
+		if esc == 'U' {
+			n = 8
+		}
+		value, ok := l.readHexDigits(n)
+		if !ok {
+			return fmt.Sprintf("invalid unicode escape sequence, expected %d hex digits after \\%c", n, esc)
+		}
+		if !utf8.ValidRune(rune(value)) {
+			return fmt.Sprintf("escape sequence \\%c%0*x is an invalid unicode code point", esc, n, value)
+		}
+		out.WriteRune(rune(value))
+	default:
+		return fmt.Sprintf("unknown escape sequence \\%c", l.ch)
+	}
+
+	return ""
+}
+
+// reads exactly n hex digits following l.ch, leaving l.ch on the last digit
+func (l *Lexer) readHexDigits(n int) (uint32, bool) {
+	var value uint32
+	for i := 0; i < n; i++ {
+		if !isHexDigit(l.peekChar()) {
+			return 0, false
+		}
+		l.readChar()
+		value = value<<4 | uint32(hexValue(l.ch))
+	}
+	return value, true
+}
+
+// reads a backtick quoted string verbatim, it may span multiple lines. Carriage returns are dropped.
+func (l *Lexer) readRawString() token.Token {
+	var out strings.Builder
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case '`':
+			l.readChar()
+			return token.Token{Type: token.STRING, Literal: out.String()}
+		case 0:
+			return illegalToken("unterminated raw string literal")
+		case '\r':
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{Filename: l.filename, Line: l.line, Column: l.column}
+}
+
+// NextToken returns the next token in the input, stamped with the position of its first character
 func (l *Lexer) NextToken() token.Token {
+	l.skipWhitespace()
+
+	for l.atComment() {
+		pos := l.currentPosition()
+		if !l.skipComments() {
+			tok := illegalToken("unterminated block comment")
+			tok.Pos = pos
+			return tok
+		}
+		l.skipWhitespace()
+	}
+
+	pos := l.currentPosition()
+	tok := l.readToken()
+	tok.Pos = pos
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
 
-	l.skipWhitespace()
+	if n := len(l.interpolations); n > 0 {
+		switch l.ch {
+		case '{':
+			l.interpolations[n-1]++
+		case '}':
+			if l.interpolations[n-1] == 0 {
+				l.interpolations = l.interpolations[:n-1]
+				return l.readString(true)
+			}
+			l.interpolations[n-1]--
+		}
+	}
 
-	if l.ch == 0 {
+	if l.ch == 0 && l.err != nil {
+		tok = illegalToken("error reading input: %s", l.err)
+		l.err = nil
+		return tok
+	} else if l.ch == 0 {
 		tok.Type = token.EOF
 		tok.Literal = ""
 	} else if toktype, ok := token.SingleCharacterToken[l.ch]; ok {
 		tok = newToken(toktype, l.ch)
-	} else if commonprefixtok, ok := token.CommonPrefixToken[l.ch]; ok {
-		if l.peekChar() == commonprefixtok.NextCharacter {
-			ch := l.ch
+	} else if toktype, ok := token.CommonPrefixToken[string(l.ch)]; ok {
+		op := string(l.ch)
+		for {
+			longer, ok := token.CommonPrefixToken[op+string(l.peekChar())]
+			if !ok {
+				break
+			}
 			l.readChar()
-			tok = token.Token{Type: commonprefixtok.MultipleCharacterType, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(commonprefixtok.SingleCharacterType, l.ch)
+			op += string(l.ch)
+			toktype = longer
 		}
-	} else if !isNotQuote(l.ch) {
-		l.readChar()
-		tok.Literal = l.readSequence(isNotQuote)
-		l.readChar()
-		tok.Type = token.STRING
-		return tok
+		tok = token.Token{Type: toktype, Literal: op}
+	} else if l.ch == '.' {
+		tok = newToken(token.DOT, l.ch)
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '.' {
+				l.readChar()
+				tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			} else {
+				tok = illegalToken("unexpected %q", "..")
+			}
+		}
+	} else if l.ch == '"' {
+		return l.readString(false)
+	} else if l.ch == '`' {
+		return l.readRawString()
 	} else {
 		if isLetter(l.ch) {
 			tok.Literal = l.readSequence(isAlphanumeric)
 			tok.Type = token.LookupGroup(tok.Literal, token.Keywords, token.IDENT)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readSequence(isDigit)
-			tok.Type = token.INT
-			return tok
+			return l.readNumber()
 		} else if l.ch == '#' {
-			l.skipComments()
-			return l.NextToken()
+			return l.readDocComment()
+		} else if l.ch == utf8.RuneError {
+			tok = illegalToken("invalid UTF-8 encoding")
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			tok = illegalToken("unexpected character %q", l.ch)
 		}
 	}
 
@@ -99,28 +407,65 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-func newToken(Type token.Type, ch byte) token.Token {
+func newToken(Type token.Type, ch rune) token.Token {
 	return token.Token{Type: Type, Literal: string(ch)}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+// ILLEGAL tokens carry a description of the problem as their literal
+func illegalToken(format string, a ...interface{}) token.Token {
+	return token.Token{Type: token.ILLEGAL, Literal: fmt.Sprintf(format, a...)}
+}
+
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch)
 }
 
-func isAlphanumeric(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || '0' <= ch && ch <= '9'
+func isAlphanumeric(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_' || unicode.IsDigit(ch)
 }
 
-func isDigit(ch byte) bool {
+// only ASCII digits make up number literals
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func isNotQuote(ch byte) bool {
-	return ch != '"'
+func isDigitOrUnderscore(ch rune) bool {
+	return isDigit(ch) || ch == '_'
+}
+
+func isHexDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile returns a lexer whose token positions refer to filename
+func NewFile(filename string, input string) *Lexer {
+	return NewFileReader(filename, strings.NewReader(input))
+}
+
+// NewReader returns a lexer that tokenizes r incrementally as tokens are requested
+func NewReader(r io.Reader) *Lexer {
+	return NewFileReader("", r)
+}
+
+// NewFileReader is NewReader with token positions referring to filename
+func NewFileReader(filename string, r io.Reader) *Lexer {
+	l := &Lexer{reader: bufio.NewReader(r), filename: filename, line: 1}
+	l.peek = l.readRune()
 	l.readChar()
 	return l
 }
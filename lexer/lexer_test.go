@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/pandeykartikey/goto/token"
 )
@@ -19,6 +23,7 @@ func TestNextToken(t *testing.T) {
 					continue;
 					break;
 				}
+				3.14 1e-9 2.5E+3 7
 				`
 
 	tests := []struct {
@@ -83,6 +88,11 @@ func TestNextToken(t *testing.T) {
 		{token.BREAK, "break"},
 		{token.SEMI, ";"},
 		{token.RBRACE, "}"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.INT, "7"},
+		{token.EOF, ""},
 	}
 
 	l := New(input)
@@ -101,3 +111,356 @@ func TestNextToken(t *testing.T) {
 	}
 
 }
+
+func TestOperators(t *testing.T) {
+	input := `+ += ++ - -= -- * *= ** **= / /= % %= = == ! != < <= > >= && || a+++b ...rest p.x`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.PLUS, "+"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INC, "++"},
+		{token.MINUS, "-"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.DEC, "--"},
+		{token.MULTIPLY, "*"},
+		{token.MULTIPLY_ASSIGN, "*="},
+		{token.POW, "**"},
+		{token.POW_ASSIGN, "**="},
+		{token.DIVIDE, "/"},
+		{token.DIVIDE_ASSIGN, "/="},
+		{token.MOD, "%"},
+		{token.MOD_ASSIGN, "%="},
+		{token.ASSIGN, "="},
+		{token.EQ, "=="},
+		{token.NOT, "!"},
+		{token.NOT_EQ, "!="},
+		{token.LT, "<"},
+		{token.LT_EQ, "<="},
+		{token.GT, ">"},
+		{token.GT_EQ, ">="},
+		{token.AND, "&&"},
+		{token.OR, "||"},
+		{token.IDENT, "a"},
+		{token.INC, "++"},
+		{token.PLUS, "+"},
+		{token.IDENT, "b"},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.IDENT, "p"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{`"say \"hi\""`, token.STRING, `say "hi"`},
+		{`"a\tb\nc\\d"`, token.STRING, "a\tb\nc\\d"},
+		{`"café"`, token.STRING, "café"},
+		{`"\x41\U0001F600"`, token.STRING, "A\U0001F600"},
+		{"`raw \\n ${x}`", token.STRING, `raw \n ${x}`},
+		{"`line one\r\nline two`", token.STRING, "line one\nline two"},
+		{`"unterminated`, token.ILLEGAL, "unterminated string literal"},
+		{"\"broken\nline\"", token.ILLEGAL, "unterminated string literal"},
+		{"`unterminated", token.ILLEGAL, "unterminated raw string literal"},
+		{`"bad \q escape"`, token.ILLEGAL, `unknown escape sequence \q`},
+		{`"\xZZ"`, token.ILLEGAL, `invalid hex escape sequence, expected 2 hex digits after \x`},
+		{`"\u12"`, token.ILLEGAL, `invalid unicode escape sequence, expected 4 hex digits after \u`},
+		{`"\UFFFFFFFF"`, token.ILLEGAL, `escape sequence \Uffffffff is an invalid unicode code point`},
+		{"@", token.ILLEGAL, `unexpected character '@'`},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("tests[%d] - Type wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestLexerResumesAfterMalformedString(t *testing.T) {
+	l := New(`"bad \q" + 1`)
+
+	expected := []token.Type{token.ILLEGAL, token.PLUS, token.INT, token.EOF}
+	for i, exp := range expected {
+		if tok := l.NextToken(); tok.Type != exp {
+			t.Fatalf("tokens[%d] - Type wrong. expected=%q, got=%q", i, exp, tok.Type)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `var a = 1;
+# comment
+  if a {
+	"x\ty" + b
+}`
+
+	tests := []struct {
+		expectedType token.Type
+		line         int
+		column       int
+	}{
+		{token.VAR, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMI, 1, 10},
+		{token.IF, 3, 3},
+		{token.IDENT, 3, 6},
+		{token.LBRACE, 3, 8},
+		{token.STRING, 4, 2},
+		{token.PLUS, 4, 9},
+		{token.IDENT, 4, 11},
+		{token.RBRACE, 5, 1},
+		{token.EOF, 5, 2},
+	}
+
+	l := NewFile("test.to", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - Type wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.Filename != "test.to" || tok.Pos.Line != tt.line || tok.Pos.Column != tt.column {
+			t.Fatalf("tests[%d] - position wrong. expected=test.to:%d:%d, got=%s", i, tt.line, tt.column, tok.Pos)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	l := NewFile("test.to", `var café, 名前 = "héllo", x_1;`)
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+		column          int
+	}{
+		{token.VAR, "var", 1},
+		{token.IDENT, "café", 5},
+		{token.COMMA, ",", 9},
+		{token.IDENT, "名前", 11},
+		{token.ASSIGN, "=", 14},
+		{token.STRING, "héllo", 16},
+		{token.COMMA, ",", 23},
+		{token.IDENT, "x_1", 25},
+		{token.SEMI, ";", 28},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - Type wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Column != tt.column {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d", i, tt.column, tok.Pos.Column)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	input := `0xFF 0o755 0b1010 1_000_000 0XdeadBEEF 1_000.5 0 x`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.INT, "0xFF"},
+		{token.INT, "0o755"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.INT, "0XdeadBEEF"},
+		{token.FLOAT, "1_000.5"},
+		{token.INT, "0"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - Type wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `# line comment
+a /* block
+comment */ + b # trailing
+## Doc comment
+##second line
+/**/c`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.PLUS, "+"},
+		{token.IDENT, "b"},
+		{token.DOC, "Doc comment"},
+		{token.DOC, "second line"},
+		{token.IDENT, "c"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - Type wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	tok := New(" /* never closed").NextToken()
+	if tok.Type != token.ILLEGAL || tok.Literal != "unterminated block comment" || tok.Pos.Column != 2 {
+		t.Fatalf("expected unterminated block comment at column 2. got=%q %q at %s", tok.Type, tok.Literal, tok.Pos)
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	input := `"hello ${name}, you have ${len({"a": 1})} items" "cost: \${x} $5" "${a}${"in ${b}"}"`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.TEMPLATE_HEAD, "hello "},
+		{token.IDENT, "name"},
+		{token.TEMPLATE_MIDDLE, ", you have "},
+		{token.IDENT, "len"},
+		{token.LPAREN, "("},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.RPAREN, ")"},
+		{token.TEMPLATE_TAIL, " items"},
+		{token.STRING, "cost: ${x} $5"},
+		{token.TEMPLATE_HEAD, ""},
+		{token.IDENT, "a"},
+		{token.TEMPLATE_MIDDLE, ""},
+		{token.TEMPLATE_HEAD, "in "},
+		{token.IDENT, "b"},
+		{token.TEMPLATE_TAIL, ""},
+		{token.TEMPLATE_TAIL, ""},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - Type wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	input := `## doc
+func héllo(x) { /* é */ return "a ${x} \u00e9" + 1_000 * 2.5e3; }
+` + "`raw\nstring`"
+
+	expected := New(input)
+	// OneByteReader makes multi-byte runes straddle separate reads
+	l := NewFileReader("stdin", iotest.OneByteReader(strings.NewReader(input)))
+
+	for i := 0; ; i++ {
+		exp, tok := expected.NextToken(), l.NextToken()
+
+		if tok.Type != exp.Type || tok.Literal != exp.Literal {
+			t.Fatalf("tokens[%d] - wrong token. expected=%q %q, got=%q %q", i, exp.Type, exp.Literal, tok.Type, tok.Literal)
+		}
+		if tok.Pos.Line != exp.Pos.Line || tok.Pos.Column != exp.Pos.Column || tok.Pos.Filename != "stdin" {
+			t.Fatalf("tokens[%d] - wrong position. expected=%s, got=%s", i, exp.Pos, tok.Pos)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+}
+
+type failingReader struct {
+	input io.Reader
+}
+
+func (r *failingReader) Read(b []byte) (int, error) {
+	n, err := r.input.Read(b)
+	if err == io.EOF {
+		return n, errors.New("disk on fire")
+	}
+	return n, err
+}
+
+func TestNewReaderError(t *testing.T) {
+	l := NewReader(&failingReader{input: strings.NewReader("a + b")})
+
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.PLUS, Literal: "+"},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.ILLEGAL, Literal: "error reading input: disk on fire"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp.Type || tok.Literal != exp.Literal {
+			t.Fatalf("tokens[%d] - wrong token. expected=%q %q, got=%q %q", i, exp.Type, exp.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
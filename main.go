@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 
 	"github.com/pandeykartikey/goto/eval"
@@ -12,34 +12,58 @@ import (
 	"github.com/pandeykartikey/goto/repl"
 )
 
+// run parses and evaluates a whole script read from r
+func run(filename string, r io.Reader) {
+	l := lexer.NewFileReader(filename, r)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		p.PrintParseErrors()
+		return
+	}
+	env := object.NewEnvironment()
+
+	result := eval.Eval(program, env)
+
+	if result != nil {
+		fmt.Println(result.Inspect())
+	}
+}
+
+// reports whether stdin is piped or redirected rather than attached to a terminal
+func stdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
 func main() {
 
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	if len(os.Args) > 2 {
-		fmt.Println("Usage:", os.Args[0], "[FILE]")
+		fmt.Println("Usage:", os.Args[0], "[FILE | -]")
+		fmt.Println("      ", os.Args[0], "tokens [FILE | -]")
+		fmt.Println("      ", os.Args[0], "ast [-json] [FILE | -]")
 		return
 	}
 
-	if len(os.Args) == 2 {
-		code, err := ioutil.ReadFile(os.Args[1])
+	if len(os.Args) == 2 && os.Args[1] != "-" {
+		file, err := os.Open(os.Args[1])
 		if err != nil {
 			fmt.Println(err.Error())
 			return
 		}
-		l := lexer.New(string(code))
-		p := parser.New(l)
+		defer file.Close()
 
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			p.PrintParseErrors()
-			return
-		}
-		env := object.NewEnvironment()
-
-		result := eval.Eval(program, env)
-
-		if result != nil {
-			fmt.Println(result.Inspect())
-		}
+		run(os.Args[1], file)
+	} else if len(os.Args) == 2 || stdinIsPipe() {
+		run("<stdin>", os.Stdin)
 	} else {
 		fmt.Println("Goto 0.1.0")
 		repl.Start()
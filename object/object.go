@@ -2,9 +2,12 @@ package object
 
 import (
 	"fmt"
+	"hash/fnv"
+	"strconv"
 	"strings"
 
 	"github.com/pandeykartikey/goto/ast"
+	"github.com/pandeykartikey/goto/token"
 )
 
 type Type string
@@ -12,6 +15,7 @@ type BuiltinFunction func(args ...Object) Object
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
@@ -21,6 +25,24 @@ const (
 	LIST_OBJ         = "LIST"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
+	MAP_OBJ          = "MAP"
+	STRUCT_OBJ       = "STRUCT"
+	INSTANCE_OBJ     = "INSTANCE"
+	MODULE_OBJ       = "MODULE"
+)
+
+// kinds of Error. A caught error exposes its kind, errors thrown by a script are GENERIC_ERROR
+// unless they say otherwise.
+const (
+	GENERIC_ERROR    = "Error"
+	RUNTIME_ERROR    = "RuntimeError"
+	TYPE_ERROR       = "TypeError"
+	NAME_ERROR       = "NameError"
+	INDEX_ERROR      = "IndexError"
+	KEY_ERROR        = "KeyError"
+	VALUE_ERROR      = "ValueError"
+	ARGUMENT_ERROR   = "ArgumentError"
+	ARITHMETIC_ERROR = "ArithmeticError"
 )
 
 type Object interface {
@@ -28,6 +50,18 @@ type Object interface {
 	Inspect() string
 }
 
+// HashKey identifies a map key. Keys of different types never compare equal.
+type HashKey struct {
+	Type  Type
+	Value uint64
+}
+
+// Hashable is implemented by objects that can be used as map keys
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
 type Integer struct {
 	Value int64
 }
@@ -40,6 +74,27 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() Type {
+	return FLOAT_OBJ
+}
+
+// Inspect always keeps a decimal point or exponent so that floats are distinguishable from integers
+func (f *Float) Inspect() string {
+	out := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(out, ".eIN") {
+		out += ".0"
+	}
+	return out
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -52,6 +107,13 @@ func (b *Boolean) Inspect() string {
 	return fmt.Sprintf("%v", b.Value)
 }
 
+func (b *Boolean) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Type: b.Type(), Value: 1}
+	}
+	return HashKey{Type: b.Type(), Value: 0}
+}
+
 type Null struct{}
 
 func (n *Null) Type() Type {
@@ -74,6 +136,12 @@ func (s *String) Inspect() string {
 	return s.Value
 }
 
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -99,8 +167,10 @@ func (l *LoopControl) Inspect() string {
 }
 
 type Function struct {
-	ParameterList *ast.IdentifierList
+	Doc           string
+	ParameterList *ast.ParameterList
 	FuncBody      *ast.BlockStatement
+	Env           *Environment // environment the function was defined in, calls extend it
 }
 
 func (f *Function) Type() Type {
@@ -110,6 +180,16 @@ func (f *Function) Type() Type {
 func (f *Function) Inspect() string {
 	var out strings.Builder
 
+	if f.Doc != "" {
+		for _, line := range strings.Split(f.Doc, "\n") {
+			out.WriteString("##")
+			if line != "" {
+				out.WriteString(" " + line)
+			}
+			out.WriteString("\n")
+		}
+	}
+
 	out.WriteString(f.ParameterList.String())
 	out.WriteString(" ")
 	out.WriteString(f.FuncBody.String())
@@ -141,6 +221,179 @@ func (l *List) Inspect() string {
 	return out.String()
 }
 
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+// Map is a hash map that remembers the order in which keys were first inserted.
+// Keys whose hashes collide share a bucket and are told apart by sameKey.
+type Map struct {
+	Pairs   []*MapPair // insertion order
+	buckets map[HashKey][]*MapPair
+}
+
+func NewMap() *Map {
+	return &Map{buckets: make(map[HashKey][]*MapPair)}
+}
+
+func (m *Map) Type() Type {
+	return MAP_OBJ
+}
+
+func (m *Map) Inspect() string {
+	var out strings.Builder
+
+	out.WriteString("{")
+	for idx, pair := range m.Pairs {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(pair.Key.Inspect())
+		out.WriteString(": ")
+		out.WriteString(pair.Value.Inspect())
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// sameKey reports whether two keys with equal hashes are the same key.
+// Hashable objects are integers, strings and booleans, whose printed form
+// identifies them within a type.
+func sameKey(a, b Object) bool {
+	return a.Type() == b.Type() && a.Inspect() == b.Inspect()
+}
+
+// find returns the pair holding key and its position in the key's bucket
+func (m *Map) find(key Hashable) (*MapPair, int) {
+	for idx, pair := range m.buckets[key.HashKey()] {
+		if sameKey(pair.Key, key) {
+			return pair, idx
+		}
+	}
+	return nil, -1
+}
+
+func (m *Map) Get(key Hashable) (Object, bool) {
+	pair, _ := m.find(key)
+	if pair == nil {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+func (m *Map) Set(key Hashable, value Object) {
+	if pair, _ := m.find(key); pair != nil {
+		pair.Value = value
+		return
+	}
+	pair := &MapPair{Key: key, Value: value}
+	hash := key.HashKey()
+	m.buckets[hash] = append(m.buckets[hash], pair)
+	m.Pairs = append(m.Pairs, pair)
+}
+
+// Delete removes key from the map and reports whether it was present
+func (m *Map) Delete(key Hashable) bool {
+	pair, pos := m.find(key)
+	if pair == nil {
+		return false
+	}
+
+	hash := key.HashKey()
+	bucket := m.buckets[hash]
+	if len(bucket) == 1 {
+		delete(m.buckets, hash)
+	} else {
+		m.buckets[hash] = append(bucket[:pos:pos], bucket[pos+1:]...)
+	}
+
+	for idx, p := range m.Pairs {
+		if p == pair {
+			m.Pairs = append(m.Pairs[:idx], m.Pairs[idx+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// StructType is a type declared with `struct`. Calling it creates an Instance.
+type StructType struct {
+	Name    string
+	Doc     string
+	Fields  *ast.ParameterList
+	Methods map[string]*Function
+	Env     *Environment // environment the struct was declared in, field defaults are evaluated in it
+}
+
+func (st *StructType) Type() Type {
+	return STRUCT_OBJ
+}
+
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + st.Fields.String() + " }"
+}
+
+// Instance is a value of a struct type, its fields are printed in declaration order
+type Instance struct {
+	Struct *StructType
+	Fields map[string]Object
+}
+
+func (i *Instance) Type() Type {
+	return INSTANCE_OBJ
+}
+
+func (i *Instance) Inspect() string {
+	var out strings.Builder
+
+	out.WriteString(i.Struct.Name)
+	out.WriteString("{")
+	for idx, field := range i.Struct.Fields.Identifiers {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(field.Value)
+		out.WriteString(": ")
+		out.WriteString(i.Fields[field.Value].Inspect())
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Module is an imported file. Its top-level names are the members of the module.
+type Module struct {
+	Name string
+	Path string
+	Env  *Environment
+}
+
+func (m *Module) Type() Type {
+	return MODULE_OBJ
+}
+
+func (m *Module) Inspect() string {
+	return "module " + m.Name + " (" + m.Path + ")"
+}
+
+// Imports is the state of the imports made by one evaluation. Every file is loaded once per
+// evaluation, however often it is imported.
+type Imports struct {
+	Modules map[string]*Module // modules that finished loading, by absolute path
+	Loading []LoadingFile      // files being loaded, outermost first, to detect import cycles
+}
+
+type LoadingFile struct {
+	Abs  string
+	Path string // as it was imported
+}
+
+func NewImports() *Imports {
+	return &Imports{Modules: make(map[string]*Module)}
+}
+
 type Builtin struct {
 	Fn BuiltinFunction
 }
@@ -155,6 +408,8 @@ func (b *Builtin) Inspect() string {
 
 type Error struct {
 	Message string
+	Kind    string
+	Pos     token.Position // position of the innermost node that produced the error
 }
 
 func (e *Error) Type() Type {
@@ -162,12 +417,23 @@ func (e *Error) Type() Type {
 }
 
 func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "Error: " + e.Pos.String() + ": " + e.Message
+	}
 	return "Error: " + e.Message
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store     map[string]Object
+	constants map[string]bool // names in store that cannot be assigned to
+	outer     *Environment
+	imports   *Imports
+	frame     *Frame // set on the environment of a function call
+}
+
+// Frame is the state of one function call that outlives the statement being evaluated
+type Frame struct {
+	Deferred []func() Object // calls registered with defer, in the order they were registered
 }
 
 func (env *Environment) Get(id string) (Object, bool) {
@@ -178,6 +444,20 @@ func (env *Environment) Get(id string) (Object, bool) {
 	return value, ok
 }
 
+// GetLocal is like Get but does not look in the enclosing environments
+func (env *Environment) GetLocal(id string) (Object, bool) {
+	value, ok := env.store[id]
+	return value, ok
+}
+
+// IsConstant reports whether id refers to a constant
+func (env *Environment) IsConstant(id string) bool {
+	if _, ok := env.store[id]; ok {
+		return env.constants[id]
+	}
+	return env.outer != nil && env.outer.IsConstant(id)
+}
+
 func (env *Environment) Create(id string, obj Object) (Object, bool) {
 	_, ok := env.store[id]
 	if ok {
@@ -187,12 +467,25 @@ func (env *Environment) Create(id string, obj Object) (Object, bool) {
 	return env.store[id], true
 }
 
+// CreateConstant is like Create but the binding can never change
+func (env *Environment) CreateConstant(id string, obj Object) (Object, bool) {
+	obj, ok := env.Create(id, obj)
+	if ok {
+		env.constants[id] = true
+	}
+	return obj, ok
+}
+
+// Update assigns obj to the binding id refers to. It fails if there is none or it is a constant.
 func (env *Environment) Update(id string, obj Object) (Object, bool) {
 	_, ok := env.Get(id)
 	if !ok {
 		return nil, false
 	}
 	if _, ok = env.store[id]; ok {
+		if env.constants[id] {
+			return nil, false
+		}
 		env.store[id] = obj
 		return env.store[id], true
 	}
@@ -200,9 +493,39 @@ func (env *Environment) Update(id string, obj Object) (Object, bool) {
 	return env.outer.Update(id, obj)
 }
 
+// SetImports makes imports the import state of env and of the environments extending it
+func (env *Environment) SetImports(imports *Imports) {
+	env.imports = imports
+}
+
+// Imports returns the import state of the nearest enclosing environment that has one, or nil
+func (env *Environment) Imports() *Imports {
+	for ; env != nil; env = env.outer {
+		if env.imports != nil {
+			return env.imports
+		}
+	}
+	return nil
+}
+
+// SetFrame marks env as the environment of the function call frame belongs to
+func (env *Environment) SetFrame(frame *Frame) {
+	env.frame = frame
+}
+
+// Frame returns the frame of the innermost function call env is part of, or nil outside functions
+func (env *Environment) Frame() *Frame {
+	for ; env != nil; env = env.outer {
+		if env.frame != nil {
+			return env.frame
+		}
+	}
+	return nil
+}
+
 func NewEnvironment() *Environment {
 	store := make(map[string]Object)
-	return &Environment{store: store, outer: nil}
+	return &Environment{store: store, constants: make(map[string]bool), outer: nil}
 }
 
 func ExtendEnv(outer *Environment) *Environment {
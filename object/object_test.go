@@ -0,0 +1,46 @@
+package object
+
+import "testing"
+
+// collidingString hashes every value to the same key
+type collidingString struct {
+	*String
+}
+
+func (c collidingString) HashKey() HashKey {
+	return HashKey{Type: STRING_OBJ, Value: 42}
+}
+
+func TestMapHashCollisions(t *testing.T) {
+	a := collidingString{&String{Value: "a"}}
+	b := collidingString{&String{Value: "b"}}
+
+	m := NewMap()
+	m.Set(a, &Integer{Value: 1})
+	m.Set(b, &Integer{Value: 2})
+
+	if len(m.Pairs) != 2 {
+		t.Fatalf("colliding keys share an entry. got=%s", m.Inspect())
+	}
+	if value, ok := m.Get(a); !ok || value.Inspect() != "1" {
+		t.Errorf("wrong value for a. got=%v", value)
+	}
+
+	m.Set(b, &Integer{Value: 3})
+	if m.Inspect() != "{a: 1, b: 3}" {
+		t.Errorf("setting b overwrote the wrong entry. got=%s", m.Inspect())
+	}
+
+	if !m.Delete(a) {
+		t.Fatalf("a was not deleted")
+	}
+	if _, ok := m.Get(a); ok {
+		t.Errorf("a is still present after delete")
+	}
+	if value, ok := m.Get(b); !ok || value.Inspect() != "3" {
+		t.Errorf("deleting a removed b. got=%v", value)
+	}
+	if m.Delete(a) {
+		t.Errorf("deleting a missing key reported success")
+	}
+}
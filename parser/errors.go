@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"github.com/pandeykartikey/goto/token"
+)
+
+// ParseError describes a single syntax error
+type ParseError struct {
+	Pos      token.Position
+	Expected []token.Type // token types that would have been accepted, empty when not known
+	Found    token.Token  // the offending token
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Message
+	}
+	return e.Message
+}
+
+// tokens that begin a statement, the parser resynchronizes in front of them after an error
+var statementStart = map[token.Type]bool{
+	token.VAR:      true,
+	token.CONST:    true,
+	token.FUNC:     true,
+	token.STRUCT:   true,
+	token.IMPORT:   true,
+	token.TRY:      true,
+	token.THROW:    true,
+	token.DEFER:    true,
+	token.IF:       true,
+	token.FOR:      true,
+	token.WHILE:    true,
+	token.SWITCH:   true,
+	token.CASE:     true,
+	token.DEFAULT:  true,
+	token.RETURN:   true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+}
+
+// recover drops the rest of the statement beginning at start that failed to parse and leaves
+// panic mode
+func (p *Parser) recover(start token.Token) {
+	p.synchronize(start)
+	p.panicking = false
+}
+
+// synchronize skips tokens until currToken begins a new statement: a statement keyword, the token
+// after a ';' or the '}' closing the enclosing block. Balanced braces are skipped as a whole.
+func (p *Parser) synchronize(start token.Token) {
+	if p.currToken == start { // always make progress
+		p.nextToken()
+	}
+
+	depth := 0
+	for !p.currTokenIs(token.EOF) {
+		switch {
+		case p.currTokenIs(token.LBRACE):
+			depth++
+		case p.currTokenIs(token.RBRACE):
+			if depth == 0 {
+				return
+			}
+			depth--
+		case p.currTokenIs(token.SEMI) && depth == 0:
+			p.nextToken()
+			return
+		case statementStart[p.currToken.Type] && depth == 0:
+			return
+		}
+
+		p.nextToken()
+	}
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/pandeykartikey/goto/ast"
 	"github.com/pandeykartikey/goto/lexer"
@@ -12,6 +15,7 @@ import (
 const ( // These represent the operator precedence values.
 	_int = iota
 	LOWEST
+	TERNARY     // X ? Y : Z
 	LOGICAL     // && or ||
 	EQUALS      // ==
 	LESSGREATER // > or <
@@ -23,6 +27,7 @@ const ( // These represent the operator precedence values.
 )
 
 var precedences = map[token.Type]int{
+	token.QUESTION: TERNARY,
 	token.AND:      LOGICAL,
 	token.OR:       LOGICAL,
 	token.EQ:       EQUALS,
@@ -39,6 +44,7 @@ var precedences = map[token.Type]int{
 	token.POW:      MULTIPLY,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 }
 
 type (
@@ -52,7 +58,11 @@ type Parser struct {
 	currToken token.Token
 	peekToken token.Token
 
-	errors []string
+	currDoc string // doc comment directly preceding currToken
+	peekDoc string
+
+	errors    []*ParseError
+	panicking bool // set after an error until the parser has resynchronized, suppressing follow-on errors
 
 	prefixParsefns map[token.Type]prefixParsefn
 	infixParsefns  map[token.Type]infixParsefn
@@ -61,7 +71,7 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*ParseError{},
 	}
 
 	p.prefixParsefns = make(map[token.Type]prefixParsefn)
@@ -71,13 +81,18 @@ func New(l *lexer.Lexer) *Parser {
 	}{
 		{token.IDENT, p.parseIdentifier},
 		{token.INT, p.parseIntegerLiteral},
+		{token.FLOAT, p.parseFloatLiteral},
 		{token.NOT, p.parsePrefixExpression},
 		{token.MINUS, p.parsePrefixExpression},
 		{token.TRUE, p.parseBoolean},
 		{token.FALSE, p.parseBoolean},
 		{token.STRING, p.parseString},
+		{token.TEMPLATE_HEAD, p.parseInterpolatedString},
 		{token.LPAREN, p.parseGroupedExpression},
 		{token.LBRACKET, p.parseList},
+		{token.FUNC, p.parseFunctionLiteral},
+		{token.LBRACE, p.parseMapLiteral},
+		{token.ILLEGAL, p.parseIllegal},
 	}
 
 	for _, fn := range prefixfns {
@@ -91,13 +106,24 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.DOT, p.parseFieldExpression)
 
 	p.setToken() // Only to be called for initialization of Parser pointers
 
 	return p
 }
 
+// Errors returns the messages of all parse errors, prefixed with their position
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for idx, err := range p.errors {
+		msgs[idx] = err.Error()
+	}
+	return msgs
+}
+
+func (p *Parser) ParseErrors() []*ParseError {
 	return p.errors
 }
 
@@ -110,14 +136,39 @@ func (p *Parser) nextToken(count ...int64) {
 		n = count[0]
 	}
 	for i := int64(0); i < n; i++ {
-		p.currToken = p.peekToken
-		p.peekToken = p.l.NextToken()
+		p.currToken, p.currDoc = p.peekToken, p.peekDoc
+		p.peekToken, p.peekDoc = p.readToken()
 	}
 }
 
 func (p *Parser) setToken() {
-	p.currToken = p.l.NextToken()
-	p.peekToken = p.l.NextToken()
+	p.currToken, p.currDoc = p.readToken()
+	p.peekToken, p.peekDoc = p.readToken()
+}
+
+// reads the next token that is not a doc comment, along with the doc comment lines directly
+// preceding it. A blank line ends a doc comment.
+func (p *Parser) readToken() (token.Token, string) {
+	var (
+		doc      []string
+		lastLine int
+	)
+
+	tok := p.l.NextToken()
+	for tok.Type == token.DOC {
+		if tok.Pos.Line > lastLine+1 {
+			doc = nil
+		}
+		doc = append(doc, tok.Literal)
+		lastLine = tok.Pos.Line
+		tok = p.l.NextToken()
+	}
+
+	if tok.Pos.Line > lastLine+1 {
+		doc = nil
+	}
+
+	return tok, strings.Join(doc, "\n")
 }
 
 func (p *Parser) currTokenIs(t token.Type) bool {
@@ -128,9 +179,20 @@ func (p *Parser) peekTokenIs(t token.Type) bool {
 	return p.peekToken.Type == t
 }
 
-func (p *Parser) tokenError(exp token.Type, t token.Type) {
-	msg := fmt.Sprintf("expected token to be %s , got %s instead", exp, t)
-	p.errors = append(p.errors, msg)
+// records an error found at tok. Only the first error of a statement is kept, the rest are
+// usually caused by it.
+func (p *Parser) addError(tok token.Token, format string, a ...interface{}) *ParseError {
+	err := &ParseError{Pos: tok.Pos, Found: tok, Message: fmt.Sprintf(format, a...)}
+	if !p.panicking {
+		p.errors = append(p.errors, err)
+		p.panicking = true
+	}
+	return err
+}
+
+func (p *Parser) tokenError(exp token.Type, tok token.Token) {
+	err := p.addError(tok, "expected token to be %s , got %s instead", exp, tok.Type)
+	err.Expected = []token.Type{exp}
 }
 
 func (p *Parser) expectCurr(t token.Type) bool {
@@ -139,7 +201,7 @@ func (p *Parser) expectCurr(t token.Type) bool {
 		return true
 	}
 
-	p.tokenError(t, p.currToken.Type)
+	p.tokenError(t, p.currToken)
 	return false
 }
 
@@ -150,7 +212,7 @@ func (p *Parser) expectPeek(t token.Type) bool {
 		return true
 	}
 
-	p.tokenError(t, p.peekToken.Type)
+	p.tokenError(t, p.peekToken)
 	return false
 }
 
@@ -170,14 +232,62 @@ func (p *Parser) parseString() ast.Expression {
 	return &ast.String{Token: p.currToken, Value: p.currToken.Literal}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.currToken}
+	str.Parts = append(str.Parts, &ast.String{Token: p.currToken, Value: p.currToken.Literal})
+
+	for {
+		p.nextToken()
+
+		if p.currTokenIs(token.TEMPLATE_MIDDLE) || p.currTokenIs(token.TEMPLATE_TAIL) {
+			p.addError(p.currToken, "empty expression in string interpolation")
+			return nil
+		}
+
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+		p.nextToken()
+
+		switch p.currToken.Type {
+		case token.TEMPLATE_MIDDLE:
+			str.Parts = append(str.Parts, &ast.String{Token: p.currToken, Value: p.currToken.Literal})
+		case token.TEMPLATE_TAIL:
+			str.Parts = append(str.Parts, &ast.String{Token: p.currToken, Value: p.currToken.Literal})
+			return str
+		default:
+			p.addError(p.currToken, "expected } to close string interpolation, got %s instead", p.currToken.Type)
+			return nil
+		}
+	}
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.currToken}
 
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 
-	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+	if errors.Is(err, strconv.ErrRange) {
+		p.addError(p.currToken, "integer literal %s overflows int64", p.currToken.Literal)
+		return nil
+	} else if err != nil {
+		p.addError(p.currToken, "could not parse %q as integer", p.currToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.currToken}
+
+	value, err := strconv.ParseFloat(p.currToken.Literal, 64)
+
+	if errors.Is(err, strconv.ErrRange) {
+		p.addError(p.currToken, "float literal %s is out of range", p.currToken.Literal)
+		return nil
+	} else if err != nil {
+		p.addError(p.currToken, "could not parse %q as float", p.currToken.Literal)
 		return nil
 	}
 
@@ -193,9 +303,17 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 }
 
-func (p *Parser) noPrefixParseFnError(t token.Type) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+func (p *Parser) parseIllegal() ast.Expression {
+	p.illegalTokenError()
+	return nil
+}
+
+func (p *Parser) illegalTokenError() {
+	p.addError(p.currToken, "ILLEGAL Token encountered: %s", p.currToken.Literal)
+}
+
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	p.addError(tok, "no prefix parse function for %s found", tok.Type)
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -254,27 +372,96 @@ func (p *Parser) parseExpressionList() *ast.ExpressionList {
 	}
 
 	if p.currTokenIs(token.EOF) {
-		p.errors = append(p.errors, "End Of File encountered while parsing")
+		p.addError(p.currToken, "End Of File encountered while parsing")
+	}
+
+	return nil
+}
+
+// parses the arguments of a call, positional ones first and then `name: value` keyword arguments.
+// Initial currtoken at the first argument and Final after the last one
+func (p *Parser) parseArgumentList() *ast.ExpressionList {
+	args := &ast.ExpressionList{Token: p.currToken}
+	keywords := make(map[string]bool)
+
+	for !p.currTokenIs(token.EOF) && !p.currTokenIs(token.RPAREN) {
+		var exp ast.Expression
+
+		if p.currTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			kwarg := &ast.KeywordArgument{Token: p.currToken, Name: p.parseIdentifier().(*ast.Identifier)}
+			if keywords[kwarg.Name.Value] {
+				p.addError(kwarg.Token, "duplicate keyword argument %s", kwarg.Name.Value)
+				return nil
+			}
+			keywords[kwarg.Name.Value] = true
+
+			p.nextToken(2)
+			kwarg.Value = p.parseExpression(LOWEST)
+			exp = kwarg
+		} else {
+			if len(keywords) > 0 {
+				p.addError(p.currToken, "positional argument follows keyword argument")
+				return nil
+			}
+			exp = p.parseExpression(LOWEST)
+		}
+		args.Expressions = append(args.Expressions, &exp)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken(2)
+			continue
+		}
+
+		p.nextToken()
+		return args
+	}
+
+	if p.currTokenIs(token.EOF) {
+		p.addError(p.currToken, "End Of File encountered while parsing")
 	}
 
 	return nil
 }
 
 func (p *Parser) parseCallExpression(left ast.Expression) ast.Expression {
-	exp := &ast.CallExpression{Token: p.currToken}
-	fname, ok := left.(*ast.Identifier)
-	if !ok {
+	exp := &ast.CallExpression{Token: p.currToken, Function: left}
+
+	p.nextToken()
+	exp.ArgumentList = p.parseArgumentList()
+
+	if !p.expectCurr(token.RPAREN) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseFieldExpression(left ast.Expression) ast.Expression {
+	exp := &ast.FieldExpression{Token: p.currToken, Left: left}
+
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
-	exp.FunctionName = fname
+	exp.Field = p.parseIdentifier().(*ast.Identifier)
+
+	return exp
+}
+
+// parses `condition ? consequence : alternative`. It is right associative, so a conditional
+// expression can be chained in the alternative without parentheses.
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{Token: p.currToken, Condition: condition}
 
 	p.nextToken()
-	exp.ArgumentList = p.parseExpressionList()
+	exp.Consequence = p.parseExpression(LOWEST)
 
-	if !p.expectCurr(token.RPAREN) {
+	if !p.expectPeek(token.COLON) {
 		return nil
 	}
 
+	p.nextToken()
+	exp.Alternative = p.parseExpression(TERNARY - 1)
+
 	return exp
 }
 
@@ -282,7 +469,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression { // returns exp
 	prefix := p.prefixParsefns[p.currToken.Type]
 
 	if prefix == nil {
-		p.noPrefixParseFnError(p.currToken.Type)
+		p.noPrefixParseFnError(p.currToken)
 		return nil
 	}
 
@@ -318,40 +505,93 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 func (p *Parser) parseAssignment(isExpression bool) *ast.Assignment {
 	assign := &ast.Assignment{IsExpression: isExpression}
 
-	if p.currTokenIs(token.VAR) {
+	if p.currTokenIs(token.VAR) || p.currTokenIs(token.CONST) {
 		assign.Token = p.currToken
 		p.nextToken()
-	}
 
-	assign.NameList = p.parseIdentifierList()
+		// only plain names can be declared
+		if names := p.parseIdentifierList(); names != nil {
+			assign.TargetList = identifierTargets(names)
+		}
 
-	if assign.Token.Type == token.VAR && !isExpression && p.currTokenIs(token.SEMI) {
-		return assign
+		if !isExpression && assign.Token.Type == token.VAR && p.currTokenIs(token.SEMI) {
+			return assign
+		}
+	} else {
+		assign.TargetList = p.parseExpressionList()
 	}
 
-	if !p.expectCurr(token.ASSIGN) {
+	return p.parseAssignmentValues(assign)
+}
+
+// parses the operator and the values of an assignment whose targets have been parsed
+func (p *Parser) parseAssignmentValues(assign *ast.Assignment) *ast.Assignment {
+	switch {
+	case assign.Token.Type == token.VAR || assign.Token.Type == token.CONST:
+		if !p.expectCurr(token.ASSIGN) {
+			return nil
+		}
+	case p.currTokenIs(token.ASSIGN) || isAssignmentOperator(p.currToken.Type):
+		assign.Token = p.currToken
+	default:
+		p.tokenError(token.ASSIGN, p.currToken)
 		return nil
 	}
 
-	if assign.Token.Type != token.VAR {
-		assign.Token = p.currToken
+	if assign.TargetList != nil {
+		for _, target := range assign.TargetList.Expressions {
+			if !isAssignable(*target) {
+				p.addError(assign.Token, "cannot assign to %s", (*target).String())
+				return nil
+			}
+		}
 	}
 
 	p.nextToken()
 
-	assign.ValueList = p.parseExpressionList()
+	if assign.Token.Type == token.INC || assign.Token.Type == token.DEC {
+		if assign.TargetList == nil || len(assign.TargetList.Expressions) != 1 {
+			p.addError(assign.Token, "%s takes a single target", assign.Token.Literal)
+		}
+	} else {
+		assign.ValueList = p.parseExpressionList()
 
-	if assign.ValueList == nil || assign.NameList == nil || len(assign.ValueList.Expressions) != len(assign.NameList.Identifiers) {
-		p.errors = append(p.errors, "Mismatch in number of values on both side of =")
+		if assign.ValueList == nil || assign.TargetList == nil || len(assign.ValueList.Expressions) != len(assign.TargetList.Expressions) {
+			p.addError(assign.Token, "Mismatch in number of values on both side of =")
+		}
 	}
 
-	if !isExpression && !p.expectCurr(token.SEMI) {
+	if !assign.IsExpression && !p.expectCurr(token.SEMI) {
 		return nil
 	}
 
 	return assign
 }
 
+func identifierTargets(names *ast.IdentifierList) *ast.ExpressionList {
+	targets := &ast.ExpressionList{Token: names.Token}
+	for _, name := range names.Identifiers {
+		var target ast.Expression = name
+		targets.Expressions = append(targets.Expressions, &target)
+	}
+	return targets
+}
+
+func isAssignmentOperator(t token.Type) bool {
+	_, ok := token.AssignmentOperators[t]
+	return ok
+}
+
+// reports whether exp can appear on the left of '='
+func isAssignable(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.FieldExpression:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.currToken}
 
@@ -381,7 +621,12 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 	for !p.currTokenIs(token.RBRACE) && !p.currTokenIs(token.EOF) {
 
+		start := p.currToken
 		stmt := p.parseStatement()
+		if p.panicking {
+			p.recover(start)
+			continue
+		}
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
@@ -408,11 +653,65 @@ func (p *Parser) parseList() ast.Expression {
 	return list
 }
 
+func (p *Parser) parseMapLiteral() ast.Expression {
+	m := &ast.MapLiteral{Token: p.currToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		m.Keys = append(m.Keys, key)
+		m.Values = append(m.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return m
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.currToken, Left: left}
 	p.nextToken()
 
+	if p.currTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, nil)
+	}
+
 	exp.Index = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(exp.Token, left, exp.Index)
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parses the rest of left[start:end] with currToken on the ':'
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
+
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
@@ -451,12 +750,104 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 	return stmt
 }
 
-func (p *Parser) parseForStatement() *ast.ForStatement {
+// parses `try { } catch name { } finally { }`. The name of the caught error is optional and so is
+// either of the catch and finally blocks, but not both.
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	if stmt.Body = p.parseBlockStatement(); stmt.Body == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(token.IDENT) {
+			p.nextToken()
+			stmt.CatchName = p.parseIdentifier().(*ast.Identifier)
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		if stmt.Catch = p.parseBlockStatement(); stmt.Catch == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		if stmt.Finally = p.parseBlockStatement(); stmt.Finally == nil {
+			return nil
+		}
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.addError(p.peekToken, "expected catch or finally after try block")
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.currToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.SEMI) {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	stmt := &ast.DeferStatement{Token: p.currToken}
+
+	p.nextToken()
+
+	exp := p.parseExpression(LOWEST)
+	if exp == nil {
+		return nil
+	}
+
+	call, ok := exp.(*ast.CallExpression)
+	if !ok {
+		p.addError(stmt.Token, "expression in defer must be function call")
+		return nil
+	}
+	stmt.Call = call
+
+	if !p.expectPeek(token.SEMI) {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
 	stmt := &ast.ForStatement{Token: p.currToken}
 
 	p.nextToken()
 
-	if !p.currTokenIs(token.SEMI) {
+	if p.currTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+		// either the loop variables of a for-in loop or the targets of a multiple assignment
+		names := p.parseIdentifierList()
+		if names == nil {
+			return nil
+		}
+		if p.currTokenIs(token.IN) {
+			return p.parseForInStatement(stmt.Token, names)
+		}
+		stmt.Init = p.parseAssignmentValues(&ast.Assignment{TargetList: identifierTargets(names), IsExpression: true})
+	} else if !p.currTokenIs(token.SEMI) {
 		stmt.Init = p.parseAssignment(true)
 	}
 
@@ -467,10 +858,10 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 
 	if !p.currTokenIs(token.SEMI) {
 		stmt.Condition = p.parseExpression(LOWEST)
-	}
 
-	if !p.expectPeek(token.SEMI) {
-		return nil
+		if !p.expectPeek(token.SEMI) {
+			return nil
+		}
 	}
 
 	p.nextToken()
@@ -487,6 +878,147 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 	return stmt
 }
 
+// parses the rest of `for names in iterable { }` with currToken on the 'in'
+func (p *Parser) parseForInStatement(tok token.Token, names *ast.IdentifierList) ast.Statement {
+	stmt := &ast.ForInStatement{Token: tok}
+
+	switch len(names.Identifiers) {
+	case 1:
+		stmt.Value = names.Identifiers[0]
+	case 2:
+		stmt.Key, stmt.Value = names.Identifiers[0], names.Identifiers[1]
+	default:
+		p.addError(tok, "for-in loops take one or two loop variables, got %d", len(names.Identifiers))
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.ForBody = p.parseBlockStatement()
+
+	return stmt
+}
+
+func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
+	stmt := &ast.SwitchStatement{Token: p.currToken}
+
+	if !p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Subject = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+
+	hasDefault := false
+	for p.currTokenIs(token.CASE) || p.currTokenIs(token.DEFAULT) {
+		if p.currTokenIs(token.DEFAULT) {
+			if hasDefault {
+				p.addError(p.currToken, "multiple defaults in switch")
+				return nil
+			}
+			hasDefault = true
+		}
+
+		clause := p.parseCaseClause()
+		if clause == nil {
+			return nil
+		}
+		stmt.Cases = append(stmt.Cases, clause)
+	}
+
+	if !p.expectCurr(token.RBRACE) {
+		return nil
+	}
+
+	if n := len(stmt.Cases); n > 0 && stmt.Cases[n-1].Fallthrough {
+		p.addError(stmt.Cases[n-1].Token, "cannot fallthrough final case in switch")
+		return nil
+	}
+
+	return stmt
+}
+
+// parses a case or default clause. It returns with currToken on the token following the clause.
+func (p *Parser) parseCaseClause() *ast.CaseClause {
+	clause := &ast.CaseClause{Token: p.currToken}
+
+	if p.currTokenIs(token.CASE) {
+		for {
+			p.nextToken()
+			clause.Values = append(clause.Values, p.parseExpression(LOWEST))
+
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken(2)
+			clause.Guard = p.parseExpression(LOWEST)
+		}
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	clause.Body = &ast.BlockStatement{Token: p.currToken}
+	p.nextToken()
+
+	for !p.currTokenIs(token.CASE) && !p.currTokenIs(token.DEFAULT) && !p.currTokenIs(token.RBRACE) && !p.currTokenIs(token.EOF) {
+		if p.currTokenIs(token.FALLTHROUGH) { // only allowed as the last statement of a clause
+			tok := p.currToken
+			if !p.expectPeek(token.SEMI) {
+				return nil
+			}
+			p.nextToken()
+			if !p.currTokenIs(token.CASE) && !p.currTokenIs(token.DEFAULT) && !p.currTokenIs(token.RBRACE) {
+				p.addError(tok, "fallthrough statement out of place")
+				return nil
+			}
+			clause.Fallthrough = true
+			break
+		}
+
+		start := p.currToken
+		stmt := p.parseStatement()
+		if p.panicking {
+			p.recover(start)
+			continue
+		}
+		if stmt != nil {
+			clause.Body.Statements = append(clause.Body.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return clause
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.currToken}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	return stmt
+}
+
 // parses identA, ... ,identZ Initial currtoken at identA and Final after identZ
 func (p *Parser) parseIdentifierList() *ast.IdentifierList {
 	identlist := &ast.IdentifierList{Token: p.currToken}
@@ -513,14 +1045,14 @@ func (p *Parser) parseIdentifierList() *ast.IdentifierList {
 	}
 
 	if p.currTokenIs(token.EOF) {
-		p.errors = append(p.errors, "End Of File encountered while parsing")
+		p.addError(p.currToken, "End Of File encountered while parsing")
 	}
 
 	return nil
 }
 
 func (p *Parser) parseFuncStatement() *ast.FuncStatement {
-	stmt := &ast.FuncStatement{Token: p.currToken}
+	stmt := &ast.FuncStatement{Token: p.currToken, Doc: p.currDoc}
 
 	p.nextToken()
 
@@ -536,28 +1068,229 @@ func (p *Parser) parseFuncStatement() *ast.FuncStatement {
 		return nil
 	}
 
-	p.nextToken()
+	stmt.ParameterList, stmt.FuncBody = p.parseFunctionParts()
+	if stmt.FuncBody == nil {
+		return nil
+	}
+
+	return stmt
+}
 
-	stmt.ParameterList = p.parseIdentifierList()
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	fn := &ast.FunctionLiteral{Token: p.currToken}
 
-	if !p.expectCurr(token.RPAREN) {
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	fn.ParameterList, fn.FuncBody = p.parseFunctionParts()
+	if fn.FuncBody == nil {
+		return nil
+	}
+
+	return fn
+}
+
+// parses `a, b = 2, ...rest`. Parameters with a default value must come after the required ones
+// and the variadic parameter, if any, must be last. Initial currtoken at the first parameter and
+// Final on the token ending the list, one of ends
+func (p *Parser) parseParameterList(ends ...token.Type) *ast.ParameterList {
+	params := &ast.ParameterList{Token: p.currToken}
+	seen := make(map[string]bool)
+
+	isEnd := func(tok token.Token) bool {
+		for _, end := range ends {
+			if tok.Type == end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for !isEnd(p.currToken) {
+		variadic := p.currTokenIs(token.ELLIPSIS)
+		if variadic {
+			p.nextToken()
+		}
+
+		if !p.expectCurr(token.IDENT) {
+			return nil
+		}
+
+		ident := p.parseIdentifier().(*ast.Identifier)
+		if seen[ident.Value] {
+			p.addError(ident.Token, "duplicate parameter %s", ident.Value)
+			return nil
+		}
+		seen[ident.Value] = true
+
+		if variadic {
+			params.Rest = ident
+			if !isEnd(p.peekToken) {
+				p.addError(p.peekToken, "variadic parameter must be last")
+				return nil
+			}
+			p.nextToken()
+			break
+		}
+
+		var value ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken(2)
+			value = p.parseExpression(LOWEST)
+		} else if count := len(params.Defaults); count > 0 && params.Defaults[count-1] != nil {
+			p.addError(ident.Token, "required parameter %s follows parameter with default value", ident.Value)
+			return nil
+		}
+
+		params.Identifiers = append(params.Identifiers, ident)
+		params.Defaults = append(params.Defaults, value)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken(2)
+			continue
+		}
+
+		if !isEnd(p.peekToken) {
+			p.tokenError(ends[0], p.peekToken)
+			return nil
+		}
+		p.nextToken()
+	}
+
+	return params
+}
+
+// parses `import "path" as name;`, the as clause is optional
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.STRING) {
 		return nil
 	}
+	stmt.Path = p.parseString().(*ast.String)
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = p.parseIdentifier().(*ast.Identifier)
+	}
+
+	if !p.expectPeek(token.SEMI) {
+		return nil
+	}
+
+	return stmt
+}
+
+// parses `struct Name { fields; methods }`. The fields are written like a parameter list and may
+// be left out, the methods like function statements.
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	stmt := &ast.StructStatement{Token: p.currToken, Doc: p.currDoc}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = p.parseIdentifier().(*ast.Identifier)
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
+	p.nextToken()
+
+	if p.currTokenIs(token.FUNC) {
+		stmt.Fields = &ast.ParameterList{Token: p.currToken}
+	} else {
+		stmt.Fields = p.parseParameterList(token.SEMI, token.RBRACE)
+		if stmt.Fields == nil {
+			return nil
+		}
+		if stmt.Fields.Rest != nil {
+			p.addError(stmt.Fields.Rest.Token, "struct fields cannot be variadic")
+			return nil
+		}
+	}
+
+	members := make(map[string]bool)
+	for _, field := range stmt.Fields.Identifiers {
+		members[field.Value] = true
+	}
 
-	stmt.FuncBody = p.parseBlockStatement()
+	for !p.currTokenIs(token.RBRACE) {
+		if p.currTokenIs(token.SEMI) {
+			p.nextToken()
+			continue
+		}
+
+		if !p.expectCurr(token.FUNC) {
+			return nil
+		}
+		method := p.parseFuncStatement()
+		if method == nil {
+			return nil
+		}
+
+		if members[method.Name.Value] {
+			p.addError(method.Name.Token, "duplicate field or method %s", method.Name.Value)
+			return nil
+		}
+		members[method.Name.Value] = true
+
+		stmt.Methods = append(stmt.Methods, method)
+		p.nextToken()
+	}
 
 	return stmt
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+// parses the parameter list and body of a function, starting with currToken on the '('
+func (p *Parser) parseFunctionParts() (*ast.ParameterList, *ast.BlockStatement) {
+	p.nextToken()
+
+	params := p.parseParameterList(token.RPAREN)
+	if params == nil {
+		return nil, nil
+	}
+
+	if !p.expectCurr(token.RPAREN) {
+		return nil, nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil, nil
+	}
+
+	return params, p.parseBlockStatement()
+}
+
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	stmt := &ast.ExpressionStatement{Token: p.currToken}
 
 	stmt.Expression = p.parseExpression(LOWEST)
 
+	if isAssignable(stmt.Expression) && (p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.ASSIGN) || isAssignmentOperator(p.peekToken.Type)) {
+		assign := &ast.Assignment{
+			TargetList: &ast.ExpressionList{Token: stmt.Token, Expressions: []*ast.Expression{&stmt.Expression}},
+		}
+
+		p.nextToken()
+		if p.currTokenIs(token.COMMA) {
+			p.nextToken()
+			rest := p.parseExpressionList()
+			if rest == nil {
+				return nil
+			}
+			assign.TargetList.Expressions = append(assign.TargetList.Expressions, rest.Expressions...)
+		}
+
+		if assign = p.parseAssignmentValues(assign); assign != nil {
+			return assign
+		}
+		return nil
+	}
+
 	if p.peekTokenIs(token.SEMI) {
 		p.nextToken()
 	}
@@ -567,7 +1300,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currToken.Type {
-	case token.VAR:
+	case token.VAR, token.CONST:
 		return p.parseAssignment(false)
 	case token.RETURN:
 		return p.parseReturnStatement()
@@ -578,21 +1311,36 @@ func (p *Parser) parseStatement() ast.Statement {
 	case token.LBRACE:
 		return p.parseBlockStatement()
 	case token.FUNC:
+		if p.peekTokenIs(token.LPAREN) { // function literal
+			return p.parseExpressionStatement()
+		}
 		return p.parseFuncStatement()
 	case token.FOR:
 		return p.parseForStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.SWITCH:
+		return p.parseSwitchStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
+	case token.FALLTHROUGH:
+		p.addError(p.currToken, "fallthrough statement out of place")
+		return nil
 	case token.ILLEGAL:
-		p.errors = append(p.errors, "ILLEGAL Token encountered")
+		p.illegalTokenError()
 		return nil
 	case token.SEMI:
 		return nil
 	case token.EOF:
 		return nil
-	case token.IDENT:
-		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.ASSIGN) {
-			return p.parseAssignment(false)
-		}
-		fallthrough
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -601,22 +1349,69 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
+	constants := make(map[string]bool)
 
 	for p.currToken.Type != token.EOF {
+		start := p.currToken
 		stmt := p.parseStatement()
 
+		if p.panicking {
+			p.recover(start)
+			// there is no enclosing block at the top level, so a '}' recovery stops on belongs
+			// to the statement that failed
+			for p.currTokenIs(token.RBRACE) {
+				p.synchronize(p.currToken)
+			}
+			continue
+		}
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+		if assign, ok := stmt.(*ast.Assignment); ok {
+			p.checkConstants(assign, constants)
+		}
 
 		p.nextToken()
 	}
 
+	// an error can be found at a token after the one that caused it
+	sort.SliceStable(p.errors, func(i, j int) bool {
+		a, b := p.errors[i].Pos, p.errors[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+
 	return program
 }
 
+// records the constants declared by a top-level assignment in constants and reports top-level
+// assignments to them, which would always fail when the program runs. The statement itself has
+// parsed fine, so this does not put the parser in panic mode.
+func (p *Parser) checkConstants(assign *ast.Assignment, constants map[string]bool) {
+	for _, target := range assign.TargetList.Expressions {
+		ident, ok := (*target).(*ast.Identifier)
+		if !ok {
+			continue
+		}
+
+		var message string
+		switch {
+		case assign.Token.Type == token.CONST:
+			constants[ident.Value] = true
+			continue
+		case !constants[ident.Value]:
+			continue
+		case assign.Token.Type == token.VAR:
+			message = "cannot redeclare constant " + ident.Value
+		default:
+			message = "cannot assign to constant " + ident.Value
+		}
+
+		p.errors = append(p.errors, &ParseError{Pos: ident.Token.Pos, Found: ident.Token, Message: message})
+	}
+}
+
 func (p *Parser) PrintParseErrors() {
-	for _, msg := range p.errors {
-		fmt.Println("Error: ", msg)
+	for _, err := range p.errors {
+		fmt.Println("Error: ", err.Error())
 	}
 }
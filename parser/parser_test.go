@@ -6,6 +6,7 @@ import (
 
 	"github.com/pandeykartikey/goto/ast"
 	"github.com/pandeykartikey/goto/lexer"
+	"github.com/pandeykartikey/goto/token"
 )
 
 func parseInput(t *testing.T, input string, n int) *ast.Program {
@@ -65,15 +66,7 @@ func testVarStatement(t *testing.T, s ast.Statement, name string) bool {
 		t.Errorf("s not *ast.Assignment. got=%T", s)
 		return false
 	}
-	if varStmt.NameList.Identifiers[0].Value != name {
-		t.Errorf("varStmt.NameList.Identifiers[0].Value not '%s'. got=%s", name, varStmt.NameList.Identifiers[0].Value)
-		return false
-	}
-	if varStmt.NameList.Identifiers[0].TokenLiteral() != name {
-		t.Errorf("s.Name not '%s'. got=%s", name, varStmt.NameList.Identifiers[0].TokenLiteral())
-		return false
-	}
-	return true
+	return testIdentifier(t, *varStmt.TargetList.Expressions[0], name)
 }
 
 func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
@@ -168,6 +161,22 @@ func testIdentifierList(t *testing.T, il ast.Expression, value []string) bool {
 	return true
 }
 
+func testParameterList(t *testing.T, params *ast.ParameterList, value []string) bool {
+	if len(params.Identifiers) != len(value) {
+		t.Errorf("params.Identifiers has wrong length. expected=%d, got=%d", len(value), len(params.Identifiers))
+		return false
+	}
+
+	for idx, ident := range params.Identifiers {
+		if ident.Value != value[idx] {
+			t.Errorf("ident.Value is not %s. got=%s", value[idx], ident.Value)
+			return false
+		}
+	}
+
+	return true
+}
+
 func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{}) bool {
 	switch v := expected.(type) {
 	case int:
@@ -231,8 +240,12 @@ func TestAssignments(t *testing.T) {
 			t.Errorf("stmt.TokenLiteral not '%s'. got=%s", tt.expTokenLiteral, stmt.TokenLiteral())
 			continue
 		}
-		if !testIdentifierList(t, stmt.NameList, tt.expIdentifierList) {
-			return
+		if len(stmt.TargetList.Expressions) != len(tt.expIdentifierList) {
+			t.Errorf("wrong number of targets. expected=%d, got=%d", len(tt.expIdentifierList), len(stmt.TargetList.Expressions))
+			continue
+		}
+		for idx, name := range tt.expIdentifierList {
+			testIdentifier(t, *stmt.TargetList.Expressions[idx], name)
 		}
 	}
 }
@@ -300,6 +313,83 @@ func TestIntegerLiteralExpression(t *testing.T) {
 
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   int64
+	}{
+		{"0xFF;", 255},
+		{"0o755;", 493},
+		{"0b1010;", 10},
+		{"1_000_000;", 1000000},
+		{"9223372036854775807;", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		expstmt := assertExpressionStatement(t, program)
+
+		lit, ok := expstmt.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", expstmt)
+		}
+		if lit.Value != tt.exp {
+			t.Errorf("lit.Value not %d. got=%d", tt.exp, lit.Value)
+		}
+	}
+}
+
+func TestIntegerLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"var a = 9223372036854775808;", "1:9: integer literal 9223372036854775808 overflows int64"},
+		{"a = 0x1_0000_0000_0000_0000;", "1:5: integer literal 0x1_0000_0000_0000_0000 overflows int64"},
+		{"0xZZ;", `1:1: could not parse "0xZZ" as integer`},
+		{"1__0;", `1:1: could not parse "1__0" as integer`},
+		{"0b102;", `1:1: could not parse "0b102" as integer`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"2.5E+3;", 2500},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		expstmt := assertExpressionStatement(t, program)
+
+		lit, ok := expstmt.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", expstmt)
+		}
+		if lit.Value != tt.exp {
+			t.Errorf("lit.Value not %v. got=%v", tt.exp, lit.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpression(t *testing.T) {
 	input := []struct {
 		input        string
@@ -510,6 +600,37 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestConditionalExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"a ? b : c", "(a ? b : c)"},
+		{"x > 1 ? x * 2 : -x", "((x > 1) ? (x * 2) : (-x))"},
+		{"a && b ? 1 : 2", "((a && b) ? 1 : 2)"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"a ? b ? c : d : e", "(a ? (b ? c : d) : e)"},
+		{"f(a ? 1 : 2, 3)", "f((a ? 1 : 2), 3)"},
+		{"l[a ? 0 : 1]", "(l[(a ? 0 : 1)])"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
+	}
+
+	exp := assertExpressionStatement(t, parseInput(t, "a ? 1 : 2", 1))
+	cond, ok := exp.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("exp is not *ast.ConditionalExpression. got=%T", exp)
+	}
+	testIdentifier(t, cond.Condition, "a")
+	testLiteralExpression(t, cond.Consequence, 1)
+	testLiteralExpression(t, cond.Alternative, 2)
+}
+
 func TestBooleanExpression(t *testing.T) {
 	input := `false;`
 
@@ -596,7 +717,7 @@ func TestFuncStatement(t *testing.T) {
 		return
 	}
 
-	if testIdentifierList(t, stmt.ParameterList, []string{"x", "y"}) {
+	if testParameterList(t, stmt.ParameterList, []string{"x", "y"}) {
 		return
 	}
 
@@ -618,6 +739,46 @@ func TestFuncStatement(t *testing.T) {
 	}
 }
 
+func TestFuncDocComments(t *testing.T) {
+	input := `## Adds two numbers.
+##
+## Both arguments must be integers.
+func add(x, y) {
+	## Not attached to anything.
+	var z = x + y;
+	## Local helper.
+	func inner() {}
+	return z;
+}
+
+## Dangling doc.
+
+func nodoc() {}`
+
+	program := parseInput(t, input, 2)
+
+	add, ok := program.Statements[0].(*ast.FuncStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FuncStatement. got=%T", program.Statements[0])
+	}
+	if add.Doc != "Adds two numbers.\n\nBoth arguments must be integers." {
+		t.Errorf("add.Doc wrong. got=%q", add.Doc)
+	}
+
+	inner, ok := add.FuncBody.Statements[1].(*ast.FuncStatement)
+	if !ok {
+		t.Fatalf("add.FuncBody.Statements[1] is not ast.FuncStatement. got=%T", add.FuncBody.Statements[1])
+	}
+	if inner.Doc != "Local helper." {
+		t.Errorf("inner.Doc wrong. got=%q", inner.Doc)
+	}
+
+	nodoc := program.Statements[1].(*ast.FuncStatement)
+	if nodoc.Doc != "" {
+		t.Errorf("nodoc.Doc wrong. got=%q", nodoc.Doc)
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2*3, 4+5)"
 
@@ -634,7 +795,7 @@ func TestCallExpressionParsing(t *testing.T) {
 		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
 	}
 
-	if !testIdentifier(t, exp.FunctionName, "add") {
+	if !testIdentifier(t, exp.Function, "add") {
 		return
 	}
 
@@ -650,86 +811,962 @@ func TestCallExpressionParsing(t *testing.T) {
 
 }
 
-func TestStringExpression(t *testing.T) {
-	input := `"Test String";`
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := "func(x, y) { x + y; }"
 
 	program := parseInput(t, input, 1)
-	expstmt := assertExpressionStatement(t, program)
+	exp := assertExpressionStatement(t, program)
 
-	if !testLiteralExpression(t, expstmt, "Test String") {
+	fn, ok := exp.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.FunctionLiteral. got=%T", exp)
+	}
+
+	if !testParameterList(t, fn.ParameterList, []string{"x", "y"}) {
 		return
 	}
 
+	if len(fn.FuncBody.Statements) != 1 {
+		t.Fatalf("function body does not contain 1 statement. got=%d", len(fn.FuncBody.Statements))
+	}
 }
 
-func TestForStatement(t *testing.T) {
-	input := `for var a = 3; a>1; a=a+1 {
-		a+1;
-	}
-	`
-	program := parseInput(t, input, 1)
-	stmt, ok := program.Statements[0].(*ast.ForStatement)
-	if !ok {
-		t.Fatalf("stmt is not ast.ForStatement. got=%T", program.Statements[0])
+func TestCallOnExpressions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"handlers[0](req)", "(handlers[0])(req)"},
+		{"makeAdder(1)(2)", "makeAdder(1)(2)"},
+		{"func(x) { return x; }(3)", "func(x) { return x; }(3)"},
+		{"var f = func() { return 1; };", "var f = func() { return 1; };"},
+		{"apply(func(x) { x * 2; }, 4)", "apply(func(x) { (x * 2) }, 4)"},
 	}
-	if !testVarStatement(t, stmt.Init, "a") {
-		return
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
 	}
-	if !testInfixExpression(t, stmt.Condition, "a", ">", 1) {
-		return
+}
+
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"func f() { return 1; }", "func f () { return 1; }"},
+		{"func f(a, b = 2) { return a; }", "func f (a,b = 2) { return a; }"},
+		{"func f(a, b = a * 2, ...rest) { return rest; }", "func f (a,b = (a * 2),...rest) { return rest; }"},
+		{"func(...args) { return args; }", "func(...args) { return args; }"},
+		{"f(1, b: 2, c: x + 1)", "f(1, b: 2, c: (x + 1))"},
 	}
-	if !testInfixExpression(t, *stmt.Update.ValueList.Expressions[0], "a", "+", 1) {
-		return
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
 	}
-	body, ok := stmt.ForBody.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("stmt.ForBody.Statements[0] is not ast.ExpressionStatement. got=%T", stmt.ForBody.Statements[0])
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"func f(a = 1, b) { }", "1:15: required parameter b follows parameter with default value"},
+		{"func f(...rest, a) { }", "1:15: variadic parameter must be last"},
+		{"func f(a, a) { }", "1:11: duplicate parameter a"},
+		{"func f(a, 1) { }", "1:11: expected token to be IDENT , got INT instead"},
+		{"f(a: 1, 2);", "1:9: positional argument follows keyword argument"},
+		{"f(a: 1, a: 2);", "1:9: duplicate keyword argument a"},
 	}
-	if !testInfixExpression(t, body.Expression, "a", "+", 1) {
-		return
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
 	}
 }
 
-func TestParsingList(t *testing.T) {
-	input := "[1, 2 * 2, 3 + 3]"
+func TestStructStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"struct Point { x, y }", "struct Point { x,y; }"},
+		{"struct Point { x, y = 0; }", "struct Point { x,y = 0; }"},
+		{"struct Empty { }", "struct Empty { }"},
+		{
+			"struct Counter { n = 0; func inc() { self.n += 1; } func get() { return self.n; } }",
+			"struct Counter { n = 0; func inc () { self.n += 1; } func get () { return self.n; } }",
+		},
+		{"struct Greeter { func hi() { return 1; } }", "struct Greeter { func hi () { return 1; } }"},
+	}
 
-	program := parseInput(t, input, 1)
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		if _, ok := program.Statements[0].(*ast.StructStatement); !ok {
+			t.Fatalf("program.Statements[0] is not ast.StructStatement. got=%T", program.Statements[0])
+		}
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
+	}
+}
 
-	expr := assertExpressionStatement(t, program)
+func TestFieldExpressions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"p.x", "p.x"},
+		{"p.x + p.y * 2", "(p.x + (p.y * 2))"},
+		{"-p.x", "(-p.x)"},
+		{"a.b.c", "a.b.c"},
+		{"l[0].x", "(l[0]).x"},
+		{"p.move(1).norm()", "p.move(1).norm()"},
+		{"p.x = 1;", "p.x = 1;"},
+		{"p.x, p.y = p.y, p.x;", "p.x, p.y = p.y, p.x;"},
+		{"p.n++;", "p.n++;"},
+	}
 
-	list, ok := expr.(*ast.List)
-	if !ok {
-		t.Fatalf("exp not ast.List. got=%T", expr)
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
 	}
-	if len(list.Elements.Expressions) != 3 {
-		t.Fatalf("len(list.Elements.Expressions) not 3. got=%d", len(list.Elements.Expressions))
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"struct { x }", "1:8: expected token to be IDENT , got { instead"},
+		{"struct P { x, x }", "1:15: duplicate parameter x"},
+		{"struct P { x; func x() { } }", "1:20: duplicate field or method x"},
+		{"struct P { ...x }", "1:15: struct fields cannot be variadic"},
+		{"struct P { x y }", "1:14: expected token to be ; , got IDENT instead"},
+		{"struct P { x; var y = 1; }", "1:15: expected token to be FUNC , got VAR instead"},
+		{"p.1", "1:3: expected token to be IDENT , got INT instead"},
 	}
-	if !testIntegerLiteral(t, *list.Elements.Expressions[0], 1) {
-		return
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
 	}
-	if !testInfixExpression(t, *list.Elements.Expressions[1], 2, "*", 2) {
-		return
+}
+
+func TestImportStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+		name  string
+	}{
+		{`import "lib/util.to" as util;`, `import "lib/util.to" as util;`, "util"},
+		{`import "lib/util.to";`, `import "lib/util.to";`, ""},
 	}
-	if !testInfixExpression(t, *list.Elements.Expressions[2], 3, "+", 3) {
-		return
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
+		if (stmt.Name == nil) != (tt.name == "") || (stmt.Name != nil && stmt.Name.Value != tt.name) {
+			t.Errorf("stmt.Name wrong. expected=%q, got=%v", tt.name, stmt.Name)
+		}
 	}
 }
 
-func TestParsingIndexExpressions(t *testing.T) {
-	input := "a[1 + 1]"
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"import util;", "1:8: expected token to be STRING , got IDENT instead"},
+		{`import "util.to" as;`, "1:20: expected token to be IDENT , got ; instead"},
+		{`import "util.to"`, "1:17: expected token to be ; , got EOF instead"},
+	}
 
-	program := parseInput(t, input, 1)
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
 
-	expr := assertExpressionStatement(t, program)
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
+	}
+}
 
-	indexExp, ok := expr.(*ast.IndexExpression)
-	if !ok {
-		t.Fatalf("expr not *ast.IndexExpression. got=%T", expr)
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"try { f(); } catch e { print(e); }", "try { f() } catch e { print(e) }"},
+		{"try { f(); } catch { g(); }", "try { f() } catch { g() }"},
+		{"try { f(); } finally { g(); }", "try { f() } finally { g() }"},
+		{"try { f(); } catch e { } finally { g(); }", "try { f() } catch e {  } finally { g() }"},
+		{`throw "boom";`, "throw boom;"},
+		{`throw Error("x", kind: "ValueError");`, "throw Error(x, kind: ValueError);"},
 	}
-	if !testIdentifier(t, indexExp.Left, "a") {
-		return
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
 	}
-	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
-		return
+}
+
+func TestTryErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"try { f(); }", "1:13: expected catch or finally after try block"},
+		{"try f(); catch { }", "1:5: expected token to be { , got IDENT instead"},
+		{"try { } catch e, f { }", "1:16: expected token to be { , got , instead"},
+		{"try { } finally g();", "1:17: expected token to be { , got IDENT instead"},
+		{`throw "boom"`, "1:13: expected token to be ; , got EOF instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
+	}
+}
+
+func TestDeferStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"defer print(x);", "defer print(x);"},
+		{"defer f.close();", "defer f.close();"},
+		{"defer func() { x = 1; }();", "defer func() { x = 1; }();"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		if _, ok := program.Statements[0].(*ast.DeferStatement); !ok {
+			t.Fatalf("program.Statements[0] is not ast.DeferStatement. got=%T", program.Statements[0])
+		}
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input string
+		exp   string
+	}{
+		{"defer x;", "1:1: expression in defer must be function call"},
+		{"defer f()", "1:10: expected token to be ; , got EOF instead"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+		n     int
+	}{
+		{"const PI = 3.14;", "const PI = 3.14;", 1},
+		{"const A, B = 1, 2;", "const A, B = 1, 2;", 1},
+		// only assignments at the top level are checked while parsing
+		{"const X = 1; func f() { X = 2; }", "const X = 1;func f () { X = 2; }", 2},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, tt.n)
+		if program.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input string
+		exp   []string
+	}{
+		{"const X;", []string{"1:8: expected token to be = , got ; instead"}},
+		{"const X[0] = 1;", []string{"1:8: expected token to be = , got [ instead"}},
+		{"const X = 1; X = 2;", []string{"1:14: cannot assign to constant X"}},
+		{"const X = 1; X += 2; X++;", []string{"1:14: cannot assign to constant X", "1:22: cannot assign to constant X"}},
+		{"const X = 1; var a, X = 1, 2;", []string{"1:21: cannot redeclare constant X"}},
+		{"const X = 1; X = 2; var y = ;", []string{"1:14: cannot assign to constant X", "1:29: no prefix parse function for ; found"}},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.exp) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q", tt.input, tt.exp, errors)
+			continue
+		}
+		for idx, err := range errors {
+			if err != tt.exp[idx] {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.exp[idx], err)
+			}
+		}
+	}
+}
+
+func TestStringExpression(t *testing.T) {
+	input := `"Test String";`
+
+	program := parseInput(t, input, 1)
+	expstmt := assertExpressionStatement(t, program)
+
+	if !testLiteralExpression(t, expstmt, "Test String") {
+		return
+	}
+
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for var a = 3; a>1; a=a+1 {
+		a+1;
+	}
+	`
+	program := parseInput(t, input, 1)
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ForStatement. got=%T", program.Statements[0])
+	}
+	if !testVarStatement(t, stmt.Init, "a") {
+		return
+	}
+	if !testInfixExpression(t, stmt.Condition, "a", ">", 1) {
+		return
+	}
+	if !testInfixExpression(t, *stmt.Update.ValueList.Expressions[0], "a", "+", 1) {
+		return
+	}
+	body, ok := stmt.ForBody.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt.ForBody.Statements[0] is not ast.ExpressionStatement. got=%T", stmt.ForBody.Statements[0])
+	}
+	if !testInfixExpression(t, body.Expression, "a", "+", 1) {
+		return
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		value    string
+		iterable string
+	}{
+		{"for item in list { print(item); }", "", "item", "list"},
+		{"for i, ch in \"abc\" { }", "i", "ch", "abc"},
+		{"for k, v in {1: 2} { }", "k", "v", "{1: 2}"},
+		{"for x in items[1:] { }", "", "x", "(items[1:])"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("statement not *ast.ForInStatement. got=%T", program.Statements[0])
+		}
+		if tt.key == "" && stmt.Key != nil {
+			t.Errorf("stmt.Key is not nil. got=%s", stmt.Key)
+		}
+		if tt.key != "" {
+			testIdentifier(t, stmt.Key, tt.key)
+		}
+		testIdentifier(t, stmt.Value, tt.value)
+		if stmt.Iterable.String() != tt.iterable {
+			t.Errorf("wrong iterable. expected=%q, got=%q", tt.iterable, stmt.Iterable.String())
+		}
+	}
+}
+
+func TestForStatementForms(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"for ;; { break; }", "for ;; { break; }"},
+		{"for ; i < 3; { }", "for ;(i < 3); {  }"},
+		{"for a, b = 0, 1; a < 5; a, b = b, a + b { }", "for a, b = 0, 1;(a < 5);a, b = b, (a + b) {  }"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("statement not *ast.ForStatement. got=%T", program.Statements[0])
+		}
+		if stmt.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, stmt.String())
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	program := parseInput(t, "while x < 10 { x += 1; }", 1)
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("statement not *ast.WhileStatement. got=%T", program.Statements[0])
+	}
+	testInfixExpression(t, stmt.Condition, "x", "<", 10)
+	if len(stmt.Body.Statements) != 1 {
+		t.Errorf("body does not contain 1 statement. got=%d", len(stmt.Body.Statements))
+	}
+}
+
+func TestSwitchStatement(t *testing.T) {
+	input := `switch cmd {
+	case "quit", "exit":
+		stop();
+	case "run" if ready:
+		run();
+		fallthrough;
+	default:
+		help();
+	}`
+
+	program := parseInput(t, input, 1)
+
+	stmt, ok := program.Statements[0].(*ast.SwitchStatement)
+	if !ok {
+		t.Fatalf("statement not *ast.SwitchStatement. got=%T", program.Statements[0])
+	}
+	testIdentifier(t, stmt.Subject, "cmd")
+
+	if len(stmt.Cases) != 3 {
+		t.Fatalf("switch does not have 3 cases. got=%d", len(stmt.Cases))
+	}
+
+	first := stmt.Cases[0]
+	if len(first.Values) != 2 {
+		t.Fatalf("first case does not have 2 values. got=%d", len(first.Values))
+	}
+	testLiteralExpression(t, first.Values[0], "quit")
+	testLiteralExpression(t, first.Values[1], "exit")
+	if len(first.Body.Statements) != 1 || first.Fallthrough {
+		t.Errorf("first case body wrong. got=%q", first.String())
+	}
+
+	second := stmt.Cases[1]
+	testIdentifier(t, second.Guard, "ready")
+	if len(second.Body.Statements) != 1 || !second.Fallthrough {
+		t.Errorf("second case body wrong. got=%q", second.String())
+	}
+
+	def := stmt.Cases[2]
+	if def.TokenLiteral() != "default" || len(def.Values) != 0 {
+		t.Errorf("third case is not the default. got=%q", def.String())
+	}
+
+	exp := `switch cmd { case quit, exit: stop() case run if ready: run() fallthrough; default: help() }`
+	if stmt.String() != exp {
+		t.Errorf("stmt.String() wrong. expected=%q, got=%q", exp, stmt.String())
+	}
+}
+
+func TestSwitchWithoutSubject(t *testing.T) {
+	program := parseInput(t, "switch { case x > 1: y; }", 1)
+
+	stmt, ok := program.Statements[0].(*ast.SwitchStatement)
+	if !ok {
+		t.Fatalf("statement not *ast.SwitchStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Subject != nil {
+		t.Errorf("stmt.Subject is not nil. got=%s", stmt.Subject)
+	}
+	testInfixExpression(t, stmt.Cases[0].Values[0], "x", ">", 1)
+}
+
+func TestSwitchErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"switch x { default: a; default: b; }", "1:24: multiple defaults in switch"},
+		{"switch x { case 1: fallthrough; }", "1:12: cannot fallthrough final case in switch"},
+		{"switch x { case 1: fallthrough; a; case 2: }", "1:20: fallthrough statement out of place"},
+		{"fallthrough;", "1:1: fallthrough statement out of place"},
+		{"switch x { case 1 a; }", "1:19: expected token to be : , got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
+	}
+}
+
+func TestParsingList(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	program := parseInput(t, input, 1)
+
+	expr := assertExpressionStatement(t, program)
+
+	list, ok := expr.(*ast.List)
+	if !ok {
+		t.Fatalf("exp not ast.List. got=%T", expr)
+	}
+	if len(list.Elements.Expressions) != 3 {
+		t.Fatalf("len(list.Elements.Expressions) not 3. got=%d", len(list.Elements.Expressions))
+	}
+	if !testIntegerLiteral(t, *list.Elements.Expressions[0], 1) {
+		return
+	}
+	if !testInfixExpression(t, *list.Elements.Expressions[1], 2, "*", 2) {
+		return
+	}
+	if !testInfixExpression(t, *list.Elements.Expressions[2], 3, "+", 3) {
+		return
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "a[1 + 1]"
+
+	program := parseInput(t, input, 1)
+
+	expr := assertExpressionStatement(t, program)
+
+	indexExp, ok := expr.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("expr not *ast.IndexExpression. got=%T", expr)
+	}
+	if !testIdentifier(t, indexExp.Left, "a") {
+		return
+	}
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"a[1:3]", "(a[1:3])"},
+		{"a[:2]", "(a[:2])"},
+		{"a[1:]", "(a[1:])"},
+		{"a[:]", "(a[:])"},
+		{"a[-2:len(a) - 1]", "(a[(-2):(len(a) - 1)])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+		expr := assertExpressionStatement(t, program)
+
+		if expr.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, expr.String())
+		}
+	}
+
+	program := parseInput(t, "a[:2]", 1)
+	slice, ok := assertExpressionStatement(t, program).(*ast.SliceExpression)
+	if !ok {
+		t.Fatalf("expr not *ast.SliceExpression")
+	}
+	if slice.Start != nil {
+		t.Errorf("slice.Start is not nil. got=%s", slice.Start)
+	}
+	testLiteralExpression(t, slice.End, 2)
+}
+
+func TestParsingMapLiterals(t *testing.T) {
+	// a '{' starting a statement opens a block, so the literal is wrapped in parentheses
+	input := `({"one": 1, "two": 1 + 1, 3: true,})`
+
+	program := parseInput(t, input, 1)
+	expr := assertExpressionStatement(t, program)
+
+	m, ok := expr.(*ast.MapLiteral)
+	if !ok {
+		t.Fatalf("expr not *ast.MapLiteral. got=%T", expr)
+	}
+	if len(m.Keys) != 3 || len(m.Values) != 3 {
+		t.Fatalf("map has wrong number of pairs. got=%d", len(m.Keys))
+	}
+
+	testLiteralExpression(t, m.Values[0], 1)
+	testInfixExpression(t, m.Values[1], 1, "+", 1)
+	testLiteralExpression(t, m.Keys[2], 3)
+	testLiteralExpression(t, m.Values[2], true)
+
+	if m.String() != "{one: 1, two: (1 + 1), 3: true}" {
+		t.Errorf("m.String() wrong. got=%q", m.String())
+	}
+}
+
+func TestParsingEmptyMapLiteral(t *testing.T) {
+	program := parseInput(t, "var m = {};", 1)
+
+	assign, ok := program.Statements[0].(*ast.Assignment)
+	if !ok {
+		t.Fatalf("statement not *ast.Assignment. got=%T", program.Statements[0])
+	}
+	m, ok := (*assign.ValueList.Expressions[0]).(*ast.MapLiteral)
+	if !ok {
+		t.Fatalf("value not *ast.MapLiteral. got=%T", *assign.ValueList.Expressions[0])
+	}
+	if len(m.Keys) != 0 {
+		t.Errorf("map is not empty. got=%d pairs", len(m.Keys))
+	}
+}
+
+func TestParsingIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`m["a"] = 1 + 2;`, `(m[a]) = (1 + 2);`},
+		{"grid[y][x] = 1;", "((grid[y])[x]) = 1;"},
+		{"a[0], a[1] = a[1], a[0];", "(a[0]), (a[1]) = (a[1]), (a[0]);"},
+		{"a, b[0] = 1, 2;", "a, (b[0]) = 1, 2;"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+
+		assign, ok := program.Statements[0].(*ast.Assignment)
+		if !ok {
+			t.Fatalf("statement not *ast.Assignment. got=%T", program.Statements[0])
+		}
+		if assign.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, assign.String())
+		}
+	}
+}
+
+func TestCompoundAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		operator string
+		exp      string
+	}{
+		{"a += 1;", "+=", "a += 1;"},
+		{"a -= b * 2;", "-=", "a -= (b * 2);"},
+		{"a[0] *= 3;", "*=", "(a[0]) *= 3;"},
+		{"a /= 2;", "/=", "a /= 2;"},
+		{"a %= 2;", "%=", "a %= 2;"},
+		{"a **= 2;", "**=", "a **= 2;"},
+		{"i++;", "++", "i++;"},
+		{"m[k]--;", "--", "(m[k])--;"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+
+		assign, ok := program.Statements[0].(*ast.Assignment)
+		if !ok {
+			t.Fatalf("statement not *ast.Assignment. got=%T", program.Statements[0])
+		}
+		if assign.TokenLiteral() != tt.operator {
+			t.Errorf("wrong operator. expected=%q, got=%q", tt.operator, assign.TokenLiteral())
+		}
+		if assign.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, assign.String())
+		}
+	}
+}
+
+func TestForStatementUpdateOperators(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"for var i = 0; i < 10; i++ { }", "i++"},
+		{"for var i = 0; i < 10; i += 2 { }", "i += 2"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("statement not *ast.ForStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Update.String() != tt.exp {
+			t.Errorf("expected=%q, got=%q", tt.exp, stmt.Update.String())
+		}
+	}
+}
+
+func TestInvalidAssignmentTargets(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"var a[0] = 1;", "1:6: expected token to be = , got [ instead"},
+		{"a, f(x) = 1, 2;", "1:9: cannot assign to f(x)"},
+		{"a, 1 = 1, 2;", "1:6: cannot assign to 1"},
+		{"var a += 1;", "1:7: expected token to be = , got += instead"},
+		{"a, b++;", "1:5: ++ takes a single target"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	input := `var a = 1;
+if a {
+	var b = ;
+}`
+
+	p := New(lexer.NewFile("test.to", input))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+
+	exp := "test.to:3:10: no prefix parse function for ; found"
+	if errors[0] != exp {
+		t.Errorf("wrong error message. expected=%q, got=%q", exp, errors[0])
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	input := `var a = ;
+var b = 5;
+func f(x) {
+	var c = 3 +;
+	return x;
+}
+var d = 1 2;
+print(b);`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+
+	expected := []string{
+		"1:9: no prefix parse function for ; found",
+		"4:13: no prefix parse function for ; found",
+		"7:11: expected token to be ; , got INT instead",
+	}
+
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d: %q", len(expected), len(errors), errors)
+	}
+	for idx, exp := range expected {
+		if errors[idx] != exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", exp, errors[idx])
+		}
+	}
+
+	// the statements around the errors are still parsed
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+	fn, ok := program.Statements[1].(*ast.FuncStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.FuncStatement. got=%T", program.Statements[1])
+	}
+	if len(fn.FuncBody.Statements) != 1 {
+		t.Errorf("function body does not contain 1 statement. got=%d", len(fn.FuncBody.Statements))
+	}
+
+	// the '}' of a failed top-level statement is not reported again
+	braceTests := []struct {
+		input string
+		exp   string
+	}{
+		{"var a = 1;\nif a > { print(a); }\nvar ok = 1;", "2:18: expected token to be : , got ; instead"},
+		{"try { }\nvar ok = 1;", "2:1: expected catch or finally after try block"},
+		{"struct S { x; func x() { } }\nvar ok = 1;", "1:20: duplicate field or method x"},
+		{"switch 1 { case 1: fallthrough; }\nvar ok = 1;", "1:12: cannot fallthrough final case in switch"},
+	}
+	for _, tt := range braceTests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 || errors[0] != tt.exp {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.exp, errors)
+		}
+		if n := len(program.Statements); n == 0 || program.Statements[n-1].String() != "var ok = 1;" {
+			t.Errorf("statement after the error not parsed for %q", tt.input)
+		}
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	p := New(lexer.New("var a = (1 + 2;"))
+	p.ParseProgram()
+
+	errs := p.ParseErrors()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got=%d", len(errs))
+	}
+
+	err := errs[0]
+	if err.Pos.Line != 1 || err.Pos.Column != 15 {
+		t.Errorf("wrong position. got=%s", err.Pos)
+	}
+	if len(err.Expected) != 1 || err.Expected[0] != token.RPAREN {
+		t.Errorf("wrong expected tokens. got=%v", err.Expected)
+	}
+	if err.Found.Type != token.SEMI {
+		t.Errorf("wrong found token. got=%s", err.Found.Type)
+	}
+	if err.Message != "expected token to be ) , got ; instead" {
+		t.Errorf("wrong message. got=%q", err.Message)
+	}
+}
+
+func TestIllegalTokenErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`var a = "abc`, "1:9: ILLEGAL Token encountered: unterminated string literal"},
+		{`"bad \q";`, `1:1: ILLEGAL Token encountered: unknown escape sequence \q`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	input := `"hello ${name}, you have ${len(items) + 1} items";`
+
+	program := parseInput(t, input, 1)
+	expr := assertExpressionStatement(t, program)
+
+	str, ok := expr.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", expr)
+	}
+	if len(str.Parts) != 5 {
+		t.Fatalf("str.Parts does not contain 5 parts. got=%d", len(str.Parts))
+	}
+
+	testString(t, str.Parts[0], "hello ")
+	testIdentifier(t, str.Parts[1], "name")
+	testString(t, str.Parts[2], ", you have ")
+	testString(t, str.Parts[4], " items")
+
+	if str.String() != "hello ${name}, you have ${(len(items) + 1)} items" {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`"a ${} b"`, "1:6: empty expression in string interpolation"},
+		{`"a ${x y} b"`, "1:8: expected } to close string interpolation, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.exp {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.exp, errors[0])
+		}
 	}
 }
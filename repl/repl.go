@@ -45,8 +45,14 @@ func Start() {
 			break
 		}
 
-		code += "\n" + line
+		if code != "" {
+			code += "\n"
+		}
+		code += line
 
+		// The parser cannot resume a half-parsed statement, so every continuation
+		// line re-lexes the pending input. code is cleared once it parses, so this
+		// is bounded by the unfinished statement, not by the whole session.
 		l := lexer.New(code)
 		p := parser.New(l)
 
@@ -1,29 +1,55 @@
 package token
 
+import "fmt"
+
 type Type string
 
+// Position is a location in the source, lines and columns start at 1
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns file:line:column, the filename is left out when it is unknown
+func (p Position) String() string {
+	if !p.IsValid() {
+		return ""
+	}
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
 type Token struct {
 	Type    Type
 	Literal string
-}
-
-type CommonPrefixTokenPair struct {
-	NextCharacter         byte
-	SingleCharacterType   Type
-	MultipleCharacterType Type
+	Pos     Position
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	DOC     = "DOC" // ## doc comment
 
 	// Identifiers
 	IDENT = "IDENT"
 
 	// Literals
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
+	// Parts of an interpolated string "head ${a} middle ${b} tail"
+	TEMPLATE_HEAD   = "TEMPLATE_HEAD"
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE"
+	TEMPLATE_TAIL   = "TEMPLATE_TAIL"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -42,11 +68,23 @@ const (
 	AND      = "&&"
 	OR       = "||"
 
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	MULTIPLY_ASSIGN = "*="
+	DIVIDE_ASSIGN   = "/="
+	MOD_ASSIGN      = "%="
+	POW_ASSIGN      = "**="
+	INC             = "++"
+	DEC             = "--"
+
 	// Delimiters
-	SEMI  = ";"
-	COLON = ":"
-	COMMA = ","
-	QUOTE = "\""
+	SEMI     = ";"
+	COLON    = ":"
+	QUESTION = "?"
+	COMMA    = ","
+	QUOTE    = "\""
+	DOT      = "."
+	ELLIPSIS = "..."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -57,6 +95,7 @@ const (
 
 	// Keywords
 	VAR      = "VAR"
+	CONST    = "CONST"
 	FUNC     = "FUNC"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
@@ -66,10 +105,26 @@ const (
 	FOR      = "FOR"
 	CONTINUE = "CONTINUE"
 	BREAK    = "BREAK"
+	WHILE    = "WHILE"
+	IN       = "IN"
+	STRUCT   = "STRUCT"
+	IMPORT   = "IMPORT"
+	AS       = "AS"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	DEFER    = "DEFER"
+
+	SWITCH      = "SWITCH"
+	CASE        = "CASE"
+	DEFAULT     = "DEFAULT"
+	FALLTHROUGH = "FALLTHROUGH"
 )
 
 var Keywords = map[string]Type{
 	"var":      VAR,
+	"const":    CONST,
 	"func":     FUNC,
 	"true":     TRUE,
 	"false":    FALSE,
@@ -79,15 +134,27 @@ var Keywords = map[string]Type{
 	"for":      FOR,
 	"continue": CONTINUE,
 	"break":    BREAK,
+	"while":    WHILE,
+	"in":       IN,
+	"struct":   STRUCT,
+	"import":   IMPORT,
+	"as":       AS,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"defer":    DEFER,
+
+	"switch":      SWITCH,
+	"case":        CASE,
+	"default":     DEFAULT,
+	"fallthrough": FALLTHROUGH,
 }
 
-var SingleCharacterToken = map[byte]Type{
-	'+': PLUS,
-	'-': MINUS,
-	'/': DIVIDE,
-	'%': MOD,
+var SingleCharacterToken = map[rune]Type{
 	';': SEMI,
 	':': COLON,
+	'?': QUESTION,
 	',': COMMA,
 	'(': LPAREN,
 	')': RPAREN,
@@ -97,14 +164,48 @@ var SingleCharacterToken = map[byte]Type{
 	']': RBRACKET,
 }
 
-var CommonPrefixToken = map[byte]CommonPrefixTokenPair{
-	'=': {NextCharacter: '=', MultipleCharacterType: EQ, SingleCharacterType: ASSIGN},
-	'*': {NextCharacter: '*', MultipleCharacterType: POW, SingleCharacterType: MULTIPLY},
-	'&': {NextCharacter: '&', MultipleCharacterType: AND, SingleCharacterType: AND},
-	'|': {NextCharacter: '|', MultipleCharacterType: OR, SingleCharacterType: OR},
-	'!': {NextCharacter: '=', MultipleCharacterType: NOT_EQ, SingleCharacterType: NOT},
-	'<': {NextCharacter: '=', MultipleCharacterType: LT_EQ, SingleCharacterType: LT},
-	'>': {NextCharacter: '=', MultipleCharacterType: GT_EQ, SingleCharacterType: GT},
+// CommonPrefixToken holds the operators that begin with the same character. Every prefix of an
+// operator is an operator too, and the lexer reads the longest one that matches.
+var CommonPrefixToken = map[string]Type{
+	"=":   ASSIGN,
+	"==":  EQ,
+	"+":   PLUS,
+	"+=":  PLUS_ASSIGN,
+	"++":  INC,
+	"-":   MINUS,
+	"-=":  MINUS_ASSIGN,
+	"--":  DEC,
+	"*":   MULTIPLY,
+	"*=":  MULTIPLY_ASSIGN,
+	"**":  POW,
+	"**=": POW_ASSIGN,
+	"/":   DIVIDE,
+	"/=":  DIVIDE_ASSIGN,
+	"%":   MOD,
+	"%=":  MOD_ASSIGN,
+	"&":   AND,
+	"&&":  AND,
+	"|":   OR,
+	"||":  OR,
+	"!":   NOT,
+	"!=":  NOT_EQ,
+	"<":   LT,
+	"<=":  LT_EQ,
+	">":   GT,
+	">=":  GT_EQ,
+}
+
+// AssignmentOperators maps compound assignment operators to the operator they apply. The
+// increment and decrement operators apply it with 1.
+var AssignmentOperators = map[Type]Type{
+	PLUS_ASSIGN:     PLUS,
+	MINUS_ASSIGN:    MINUS,
+	MULTIPLY_ASSIGN: MULTIPLY,
+	DIVIDE_ASSIGN:   DIVIDE,
+	MOD_ASSIGN:      MOD,
+	POW_ASSIGN:      POW,
+	INC:             PLUS,
+	DEC:             MINUS,
 }
 
 func LookupGroup(s string, m map[string]Type, def Type) Type { // def default token type